@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// broadcastResult is one window's outcome of a command sent to all windows.
+type broadcastResult struct {
+	WindowID string          `json:"windowId"`
+	Success  bool            `json:"success"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Code     string          `json:"code,omitempty"`
+}
+
+// allWindowsArg returns whether the command should be sent to every window.
+// allWindows is mutually exclusive with windowId.
+func allWindowsArg(args map[string]any) (bool, error) {
+	if err := optionalBool(args, "allWindows"); err != nil {
+		return false, err
+	}
+	allWindows, _ := args["allWindows"].(bool)
+	if allWindows && windowIdArg(args) != "" {
+		return false, fmt.Errorf("pass either 'windowId' or 'allWindows', not both")
+	}
+	return allWindows, nil
+}
+
+// broadcastCommand sends the command to every active window in parallel and
+// returns the per-window results as a JSON array sorted by windowId. Failing
+// windows are reported in their entry and don't fail the whole call.
+func broadcastCommand(ctx context.Context, toolName string, argsJson json.RawMessage, timeout time.Duration) (*mcp.CallToolResult, error) {
+	windows, warning, err := getActiveWindows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active windows: %v", err)
+	}
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no VS Code windows found")
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]broadcastResult, 0, len(windows))
+	for id := range windows {
+		wg.Add(1)
+		go func(windowId string) {
+			defer wg.Done()
+			result := broadcastResult{WindowID: windowId}
+			response, err := writeCommand(windowId, newCommand(toolName, argsJson), timeout)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Success = response.Success
+				result.Data = response.Data
+				result.Error = response.Error
+				result.Code = response.Code
+			}
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].WindowID < results[j].WindowID
+	})
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal results: %v", err)
+	}
+
+	result := mcp.NewToolResultText(limitResponse(string(data), maxResponseBytes))
+	if warning != "" {
+		result.Content = append(result.Content, mcp.NewTextContent("Warning: "+warning))
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// serverVersion is the version of the MCP server reported to MCP clients.
+const serverVersion = "1.0.0"
+
+// serverCapabilities is what the MCP server itself supports.
+type serverCapabilities struct {
+	Version   string   `json:"version"`
+	Tools     []string `json:"tools"`
+	ItemTypes []string `json:"itemTypes"`
+}
+
+// extensionCapabilities is what the connected extension reports. Error is set
+// instead if the extension couldn't be asked, e.g. because it predates the
+// capabilities command.
+type extensionCapabilities struct {
+	WindowID  string   `json:"windowId,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Tools     []string `json:"tools,omitempty"`
+	ItemTypes []string `json:"itemTypes,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type capabilitiesResult struct {
+	Server    serverCapabilities    `json:"server"`
+	Extension extensionCapabilities `json:"extension"`
+}
+
+// handleCapabilities reports the tools and open item types of the MCP server
+// and of the target window's extension, so clients can feature-detect instead
+// of trying calls that the other side doesn't support.
+func handleCapabilities(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	windowIdStr := windowIdArg(request.GetArguments())
+	if allWindows, _ := request.GetArguments()["allWindows"].(bool); allWindows {
+		return nil, fmt.Errorf("capabilities does not support allWindows, ask each window by windowId")
+	}
+	if err := validateSchema("capabilities", request.GetArguments()); err != nil {
+		return nil, err
+	}
+
+	tools := make([]string, 0, len(toolSchemas))
+	for name := range toolSchemas {
+		tools = append(tools, name)
+	}
+	sort.Strings(tools)
+	result := capabilitiesResult{
+		Server: serverCapabilities{Version: serverVersion, Tools: tools, ItemTypes: openItemTypes()},
+	}
+
+	// The server's part is still useful if no extension answers
+	result.Extension = queryExtensionCapabilities(ctx, windowIdStr)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal capabilities: %v", err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func queryExtensionCapabilities(ctx context.Context, windowIdStr string) extensionCapabilities {
+	windowId, err := getTargetWindow(ctx, &windowIdStr)
+	if err != nil {
+		return extensionCapabilities{Error: err.Error()}
+	}
+
+	response, err := writeCommand(windowId, newCommand("capabilities", json.RawMessage("{}")), commandTimeout)
+	if err != nil {
+		return extensionCapabilities{WindowID: windowId, Error: err.Error()}
+	}
+	if !response.Success {
+		return extensionCapabilities{WindowID: windowId, Error: response.Error}
+	}
+
+	var capabilities extensionCapabilities
+	if err := json.Unmarshal(response.Data, &capabilities); err != nil {
+		return extensionCapabilities{WindowID: windowId, Error: fmt.Sprintf("invalid capabilities response: %v", err)}
+	}
+	capabilities.WindowID = windowId
+	return capabilities
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// commandLogDir enables per-window NDJSON command logs when set via
+// VS_CLAUDE_LOG_DIR. Logging to stderr is unaffected.
+var commandLogDir = os.Getenv("VS_CLAUDE_LOG_DIR")
+
+const (
+	// maxCommandLogSize is the size at which a window's log is rotated
+	maxCommandLogSize = 5 * 1024 * 1024
+	// commandLogBackups is how many rotated logs are kept per window
+	commandLogBackups = 3
+)
+
+// commandLogMu serializes log writes and rotation within this process
+var commandLogMu sync.Mutex
+
+// commandLogEntry is a single line in a window's command log
+type commandLogEntry struct {
+	Time      time.Time `json:"time"`
+	ID        string    `json:"id"`
+	Tool      string    `json:"tool"`
+	ArgsBytes int       `json:"argsBytes"`
+	Success   bool      `json:"success"`
+	LatencyMs float64   `json:"latencyMs"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// logCommand appends an entry for a finished command to the window's log in
+// commandLogDir, if enabled. Failures are reported on stderr and otherwise
+// ignored, the audit trail must never break a command.
+func logCommand(windowId string, cmd Command, latency time.Duration, response *CommandResponse, err error) {
+	if commandLogDir == "" {
+		return
+	}
+
+	entry := commandLogEntry{
+		Time:      time.Now(),
+		ID:        cmd.ID,
+		Tool:      cmd.Tool,
+		ArgsBytes: len(cmd.Args),
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+	switch {
+	case err != nil:
+		entry.Error = err.Error()
+	case response != nil:
+		entry.Success = response.Success
+		entry.Error = response.Error
+	}
+
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		logWarnf("Failed to marshal command log entry: %v", marshalErr)
+		return
+	}
+
+	commandLogMu.Lock()
+	defer commandLogMu.Unlock()
+
+	if err := os.MkdirAll(commandLogDir, 0700); err != nil {
+		logWarnf("Failed to create command log directory: %v", err)
+		return
+	}
+	logFile := filepath.Join(commandLogDir, windowId+".ndjson")
+	rotateCommandLog(logFile)
+
+	f, openErr := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if openErr != nil {
+		logWarnf("Failed to open command log: %v", openErr)
+		return
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "%s\n", line); err != nil {
+		logWarnf("Failed to write command log: %v", err)
+	}
+}
+
+// rotateCommandLog shifts logFile to logFile.1, logFile.1 to logFile.2 and
+// so on once it exceeds maxCommandLogSize, dropping the oldest backup.
+func rotateCommandLog(logFile string) {
+	info, err := os.Stat(logFile)
+	if err != nil || info.Size() < maxCommandLogSize {
+		return
+	}
+	for i := commandLogBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", logFile, i), fmt.Sprintf("%s.%d", logFile, i+1))
+	}
+	if err := os.Rename(logFile, logFile+".1"); err != nil {
+		logWarnf("Failed to rotate command log: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultCommandResults is how many command IDs listCommands returns unless
+// maxResults says otherwise, maxCommandResults bounds maxResults. VS Code
+// registers thousands of commands, far more than fit a tool result.
+const (
+	defaultCommandResults = 100
+	maxCommandResults     = 1000
+)
+
+// commandList is the result of the listCommands tool.
+type commandList struct {
+	Commands  []string `json:"commands"`
+	Total     int      `json:"total"`
+	Truncated bool     `json:"truncated"`
+}
+
+// handleListCommands asks the target window for its registered command IDs.
+// The filter and result limit are applied here as well, so the result stays
+// bounded whatever the extension returns.
+func handleListCommands(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	windowIdStr := windowIdArg(args)
+	if allWindows, _ := args["allWindows"].(bool); allWindows {
+		return nil, fmt.Errorf("listCommands does not support allWindows, list each window's commands by windowId")
+	}
+	if err := validateSchema("listCommands", args); err != nil {
+		return nil, err
+	}
+	if err := validateToolArgs("listCommands", args); err != nil {
+		return nil, err
+	}
+	filter := request.GetString("filter", "")
+	includeInternal := request.GetBool("includeInternal", false)
+	maxResults := request.GetInt("maxResults", defaultCommandResults)
+
+	windowId, err := getTargetWindow(ctx, &windowIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	argsJson, err := json.Marshal(map[string]any{"filter": filter, "includeInternal": includeInternal})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal arguments: %v", err)
+	}
+	response, err := writeCommand(windowId, newCommand("listCommands", argsJson), commandTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute listCommands: %v", err)
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("listCommands failed: %s", response.Error)
+	}
+
+	var ids []string
+	if err := json.Unmarshal(response.Data, &ids); err != nil {
+		return nil, fmt.Errorf("unexpected listCommands response: %v", err)
+	}
+	result := filterCommands(ids, filter, includeInternal, maxResults)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal commands: %v", err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// filterCommands keeps the command IDs containing filter, case-insensitive,
+// and drops internal ones (starting with an underscore) unless asked for.
+// The matches are sorted and cut off after maxResults.
+func filterCommands(ids []string, filter string, includeInternal bool, maxResults int) commandList {
+	filter = strings.ToLower(filter)
+	matches := []string{}
+	for _, id := range ids {
+		if !includeInternal && strings.HasPrefix(id, "_") {
+			continue
+		}
+		if strings.Contains(strings.ToLower(id), filter) {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+
+	result := commandList{Commands: matches, Total: len(matches)}
+	if len(matches) > maxResults {
+		result.Commands = matches[:maxResults]
+		result.Truncated = true
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// staleThreshold is how long a window's metadata may go without a heartbeat
+// before the window is considered gone. Override with VS_CLAUDE_STALE_MS.
+var staleThreshold = envMilliseconds("VS_CLAUDE_STALE_MS", 5*time.Second)
+
+// commandTimeout is how long to wait for the extension to answer a command.
+// Override with VS_CLAUDE_TIMEOUT_MS.
+var commandTimeout = envMilliseconds("VS_CLAUDE_TIMEOUT_MS", 30*time.Second)
+
+// interactiveTimeout is how long to wait for commands that block on user
+// input, like a message with actions. Override with
+// VS_CLAUDE_INTERACTIVE_TIMEOUT_MS.
+var interactiveTimeout = envMilliseconds("VS_CLAUDE_INTERACTIVE_TIMEOUT_MS", 5*time.Minute)
+
+// pollInterval is how often the response file is checked for new data.
+// Override with VS_CLAUDE_POLL_MS, between minPollInterval and maxPollInterval.
+var pollInterval = envPollInterval("VS_CLAUDE_POLL_MS", 50*time.Millisecond)
+
+const (
+	minPollInterval = 5 * time.Millisecond
+	maxPollInterval = time.Second
+)
+
+// defaultWatchSeconds is how long watchDiagnostics streams updates by default,
+// maxWatchDuration bounds it.
+const (
+	defaultWatchSeconds = 30
+	maxWatchDuration    = 5 * time.Minute
+)
+
+// timeoutFor returns the response timeout for a tool call.
+func timeoutFor(toolName string, args map[string]any) time.Duration {
+	if toolName == "watchDiagnostics" {
+		// The final response only arrives once the watch ends
+		seconds, ok := args["duration"].(float64)
+		if !ok {
+			seconds = defaultWatchSeconds
+		}
+		return time.Duration(seconds*float64(time.Second)) + commandTimeout
+	}
+	if toolName == "showMessage" {
+		if actions, ok := args["actions"].([]any); ok && len(actions) > 0 {
+			return interactiveTimeout
+		}
+	}
+	return commandTimeout
+}
+
+// maxResponseBytes caps the size of a tool result, larger results are
+// truncated. Override with VS_CLAUDE_MAX_RESPONSE_BYTES, 0 disables the limit.
+var maxResponseBytes = envBytes("VS_CLAUDE_MAX_RESPONSE_BYTES", 1<<20)
+
+// staleCleanupFactor is how many stale thresholds must pass before a stale
+// window's files are removed, so a briefly lagging heartbeat never loses files.
+const staleCleanupFactor = 3
+
+// envMilliseconds reads a positive duration in milliseconds from the given
+// environment variable, falling back to the default if unset or invalid.
+func envMilliseconds(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	ms, err := strconv.Atoi(value)
+	if err != nil || ms <= 0 {
+		logWarnf("Ignoring invalid %s=%q, using default %v", name, value, defaultValue)
+		return defaultValue
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// envPollInterval reads the poll interval like envMilliseconds, falling back
+// to the default if it is outside minPollInterval and maxPollInterval.
+func envPollInterval(name string, defaultValue time.Duration) time.Duration {
+	interval := envMilliseconds(name, defaultValue)
+	if interval < minPollInterval || interval > maxPollInterval {
+		logWarnf("Ignoring %s=%q outside %v-%v, using default %v", name, os.Getenv(name), minPollInterval, maxPollInterval, defaultValue)
+		return defaultValue
+	}
+	return interval
+}
+
+// envBytes reads a non-negative byte count from the given environment
+// variable, falling back to the default if unset or invalid.
+func envBytes(name string, defaultValue int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logWarnf("Ignoring invalid %s=%q, using default %d", name, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// windowQueue is how commands to the same window are ordered. Override with
+// VS_CLAUDE_WINDOW_QUEUE: "off" sends them right away, even while others are
+// in flight; "wait" sends one at a time, later commands waiting their turn;
+// "busy" fails a command with WINDOW_BUSY while another one is in flight.
+var windowQueue = envChoice("VS_CLAUDE_WINDOW_QUEUE", []string{"off", "wait", "busy"}, "off")
+
+// envChoice reads one of the given values from the environment variable,
+// falling back to the default if unset or invalid.
+func envChoice(name string, choices []string, defaultValue string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	for _, choice := range choices {
+		if value == choice {
+			return value
+		}
+	}
+	logWarnf("Ignoring invalid %s=%q, using default %s", name, value, defaultValue)
+	return defaultValue
+}
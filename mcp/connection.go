@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// pingTimeout bounds each individual ping, so one lost ping doesn't stall a
+// whole diagnosis run.
+const pingTimeout = 2 * time.Second
+
+// pingResult is the result of the ping tool. Extension holds the extension's
+// echo, typically its timestamp and version.
+type pingResult struct {
+	WindowID  string          `json:"windowId"`
+	LatencyMs float64         `json:"latencyMs"`
+	Extension json.RawMessage `json:"extension,omitempty"`
+}
+
+// handlePing sends a side-effect free ping to the target window and reports
+// the round-trip latency measured here together with the extension's echo.
+func handlePing(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	windowIdStr := windowIdArg(request.GetArguments())
+	if allWindows, _ := request.GetArguments()["allWindows"].(bool); allWindows {
+		return nil, fmt.Errorf("ping does not support allWindows, ping each window by windowId")
+	}
+	if err := validateSchema("ping", request.GetArguments()); err != nil {
+		return nil, err
+	}
+	windowId, err := getTargetWindow(ctx, &windowIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	response, err := writeCommand(windowId, newCommand("ping", json.RawMessage("{}")), commandTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute ping: %v", err)
+	}
+	if !response.Success {
+		return nil, fmt.Errorf("ping failed: %s", response.Error)
+	}
+
+	result := pingResult{
+		WindowID:  windowId,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Extension: response.Data,
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ping result: %v", err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// connectionDiagnosis summarizes the round trips of a diagnoseConnection run.
+// Latencies are in milliseconds.
+type connectionDiagnosis struct {
+	WindowID  string   `json:"windowId"`
+	Count     int      `json:"count"`
+	Succeeded int      `json:"succeeded"`
+	MinMs     float64  `json:"minMs"`
+	MaxMs     float64  `json:"maxMs"`
+	AvgMs     float64  `json:"avgMs"`
+	JitterMs  float64  `json:"jitterMs"`
+	Failures  []string `json:"failures"`
+}
+
+// handleDiagnoseConnection sends a series of ping commands to the target
+// window and reports round-trip statistics. It never fails because of
+// individual pings, those are reported as failures instead.
+func handleDiagnoseConnection(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	windowIdStr := windowIdArg(args)
+	if allWindows, _ := args["allWindows"].(bool); allWindows {
+		return nil, fmt.Errorf("diagnoseConnection does not support allWindows, diagnose each window by windowId")
+	}
+
+	if err := validateSchema("diagnoseConnection", args); err != nil {
+		return nil, err
+	}
+	count := request.GetInt("count", 5)
+
+	windowId, err := getTargetWindow(ctx, &windowIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnosis := connectionDiagnosis{WindowID: windowId, Count: count, Failures: []string{}}
+	var latencies []float64
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		response, err := writeCommand(windowId, newCommand("ping", json.RawMessage("{}")), pingTimeout)
+		elapsed := float64(time.Since(start).Microseconds()) / 1000
+		if err != nil {
+			diagnosis.Failures = append(diagnosis.Failures, fmt.Sprintf("ping %d: %v", i+1, err))
+			continue
+		}
+		if !response.Success {
+			diagnosis.Failures = append(diagnosis.Failures, fmt.Sprintf("ping %d: %s", i+1, response.Error))
+			continue
+		}
+		latencies = append(latencies, elapsed)
+	}
+
+	diagnosis.Succeeded = len(latencies)
+	if len(latencies) > 0 {
+		diagnosis.MinMs, diagnosis.MaxMs = latencies[0], latencies[0]
+		sum := 0.0
+		for _, l := range latencies {
+			sum += l
+			diagnosis.MinMs = min(diagnosis.MinMs, l)
+			diagnosis.MaxMs = max(diagnosis.MaxMs, l)
+		}
+		diagnosis.AvgMs = sum / float64(len(latencies))
+
+		// Jitter is the mean difference between consecutive round trips
+		if len(latencies) > 1 {
+			diffs := 0.0
+			for i := 1; i < len(latencies); i++ {
+				diff := latencies[i] - latencies[i-1]
+				if diff < 0 {
+					diff = -diff
+				}
+				diffs += diff
+			}
+			diagnosis.JitterMs = diffs / float64(len(latencies)-1)
+		}
+	}
+	logInfof("Connection diagnosis for %s: %d/%d pings succeeded, avg %.1fms", windowId, diagnosis.Succeeded, count, diagnosis.AvgMs)
+
+	data, err := json.Marshal(diagnosis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal diagnosis: %v", err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
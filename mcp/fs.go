@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// fileSystem is the file system the IPC protocol runs on. Command and window
+// files are only accessed through ipcFS, so tests can substitute one that
+// plays the extension's part.
+type fileSystem interface {
+	Open(name string) (readableFile, error)
+	OpenFile(name string, flag int, perm os.FileMode) (writableFile, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	Truncate(name string, size int64) error
+	Remove(name string) error
+}
+
+// readableFile is a file opened for reading, like the response file.
+type readableFile interface {
+	io.ReadSeekCloser
+	Stat() (os.FileInfo, error)
+}
+
+// writableFile is a file opened for writing, like the command file.
+type writableFile interface {
+	io.WriteCloser
+	Sync() error
+}
+
+// ipcFS is the file system used for IPC, the real one outside of tests.
+var ipcFS fileSystem = osFileSystem{}
+
+// osFileSystem implements fileSystem with the os package.
+type osFileSystem struct{}
+
+func (osFileSystem) Open(name string) (readableFile, error) {
+	return os.Open(name)
+}
+
+func (osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (writableFile, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFileSystem) Truncate(name string, size int64) error {
+	return os.Truncate(name, size)
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
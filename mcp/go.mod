@@ -4,10 +4,14 @@ go 1.23.2
 
 toolchain go1.24.4
 
-require github.com/mark3labs/mcp-go v0.32.0
+require (
+	github.com/gofrs/flock v0.12.1
+	github.com/google/uuid v1.6.0
+	github.com/mark3labs/mcp-go v0.32.0
+)
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/spf13/cast v1.7.1 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
+	golang.org/x/sys v0.22.0 // indirect
 )
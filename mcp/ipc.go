@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// pendingCommands tracks in-flight command IDs per window, so stale window
+// cleanup never deletes files a command is still using, and response files
+// are never compacted while a command waits for its response.
+var pendingCommands = struct {
+	sync.Mutex
+	ids map[string]map[string]bool
+}{ids: make(map[string]map[string]bool)}
+
+func beginCommand(windowId, id string) {
+	pendingCommands.Lock()
+	defer pendingCommands.Unlock()
+	if pendingCommands.ids[windowId] == nil {
+		pendingCommands.ids[windowId] = make(map[string]bool)
+	}
+	pendingCommands.ids[windowId][id] = true
+}
+
+func endCommand(windowId, id string) {
+	pendingCommands.Lock()
+	defer pendingCommands.Unlock()
+	delete(pendingCommands.ids[windowId], id)
+	if len(pendingCommands.ids[windowId]) == 0 {
+		delete(pendingCommands.ids, windowId)
+	}
+}
+
+func hasPendingCommands(windowId string) bool {
+	pendingCommands.Lock()
+	defer pendingCommands.Unlock()
+	return len(pendingCommands.ids[windowId]) > 0
+}
+
+func pendingCommandCount() int {
+	pendingCommands.Lock()
+	defer pendingCommands.Unlock()
+	count := 0
+	for _, ids := range pendingCommands.ids {
+		count += len(ids)
+	}
+	return count
+}
+
+// shutdown is closed once the server shuts down. Commands still waiting for a
+// response then fail with errShuttingDown, and no new commands are written.
+var shutdown = make(chan struct{})
+
+var errShuttingDown = errors.New("server shutting down")
+
+// errNoResponse and errExtensionGone report a command that was written to the
+// command file but never answered, because the extension timed out or its
+// response file is gone.
+var (
+	errNoResponse    = errors.New("timeout waiting for response")
+	errExtensionGone = errors.New("extension not responding")
+)
+
+// shutdownGracePeriod is how long in-flight commands may take to finish
+// after a shutdown was requested.
+const shutdownGracePeriod = 2 * time.Second
+
+// shutdownCommands waits up to shutdownGracePeriod for in-flight commands to
+// finish, then makes the remaining ones give up.
+func shutdownCommands() {
+	deadline := time.Now().Add(shutdownGracePeriod)
+	for pendingCommandCount() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if count := pendingCommandCount(); count > 0 {
+		logWarnf("Abandoning %d in-flight command(s)", count)
+	}
+	close(shutdown)
+}
+
+// pollWait sleeps for one poll interval. It returns errShuttingDown early if
+// the server shuts down meanwhile.
+func pollWait() error {
+	select {
+	case <-shutdown:
+		return errShuttingDown
+	case <-time.After(pollInterval):
+		return nil
+	}
+}
+
+// writeCommand writes a command and waits for its final response. Partial
+// responses are collected, see streamCommand.
+func writeCommand(windowId string, cmd Command, timeout time.Duration) (*CommandResponse, error) {
+	return streamCommand(windowId, cmd, timeout, nil)
+}
+
+// streamCommand writes a command and waits for its final response, calling
+// onPartial (if set) for each partial response received before it. Every
+// partial response extends the deadline by timeout, so long-running commands
+// that report progress don't time out. If the final response carries no data,
+// the data of all partial responses is combined into a JSON array.
+func streamCommand(windowId string, cmd Command, timeout time.Duration, onPartial func(*CommandResponse)) (response *CommandResponse, err error) {
+	select {
+	case <-shutdown:
+		return nil, errShuttingDown
+	default:
+	}
+	release, err := acquireWindow(windowId, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if !hasPendingCommands(windowId) {
+		compactResponseFile(windowId)
+	}
+	beginCommand(windowId, cmd.ID)
+	defer endCommand(windowId, cmd.ID)
+
+	sent := time.Now()
+	defer func() {
+		logCommand(windowId, cmd, time.Since(sent), response, err)
+	}()
+
+	// Register with the window's response reader before writing, so the
+	// response can't be missed
+	reader := watchResponses(windowId, cmd.ID)
+	defer reader.stopWatching(cmd.ID)
+
+	// Write the command
+	cmdFile := filepath.Join(vsClaudeDir, fmt.Sprintf("%s.in", windowId))
+	if err := appendCommand(cmdFile, cmd, timeout); err != nil {
+		return nil, err
+	}
+
+	// Set up timeout
+	start := time.Now()
+	deadline := start.Add(timeout)
+	loggedWaiting := false
+
+	// Data of partial responses received so far
+	var partials []json.RawMessage
+
+	// Poll for response every pollInterval until timeout
+	for time.Now().Before(deadline) {
+		if err := reader.poll(); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			// Response file doesn't exist, extension might still be starting up
+			if time.Since(start) > responseFileGracePeriod {
+				return nil, fmt.Errorf("%w (response file never created) for window %s", errExtensionGone, windowId)
+			}
+			if !loggedWaiting {
+				logInfof("Waiting for extension to come online for window %s", windowId)
+				loggedWaiting = true
+			}
+		}
+
+		for _, resp := range reader.take(cmd.ID) {
+			if resp.Partial {
+				if len(resp.Data) > 0 {
+					partials = append(partials, resp.Data)
+				}
+				if onPartial != nil {
+					onPartial(&resp)
+				}
+				deadline = time.Now().Add(timeout)
+				continue
+			}
+
+			if len(resp.Data) == 0 && len(partials) > 0 {
+				combined, err := json.Marshal(partials)
+				if err != nil {
+					return nil, fmt.Errorf("failed to combine partial responses: %v", err)
+				}
+				resp.Data = combined
+			}
+			return &resp, nil
+		}
+
+		// Wait a bit before next check
+		if err := pollWait(); err != nil {
+			return nil, err
+		}
+	}
+
+	if malformedLine := reader.malformed(cmd.ID); malformedLine != "" {
+		return nil, fmt.Errorf("%w to command %s, received malformed response: %s", errNoResponse, cmd.ID, truncate(malformedLine, maxLoggedLineLength))
+	}
+	return nil, fmt.Errorf("%w to command %s", errNoResponse, cmd.ID)
+}
+
+// checkVsClaudeDir makes sure vsClaudeDir exists, creating it if needed, and
+// that the current user can create files in it.
+func checkVsClaudeDir() error {
+	if err := os.MkdirAll(vsClaudeDir, 0700); err != nil {
+		return withPermissionHint(fmt.Errorf("failed to create %s: %w", vsClaudeDir, err))
+	}
+	probe, err := os.CreateTemp(vsClaudeDir, ".write-check-*")
+	if err != nil {
+		return withPermissionHint(fmt.Errorf("%s is not writable: %w", vsClaudeDir, err))
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// withPermissionHint adds a hint about directory ownership to permission
+// errors, which otherwise only name the failing syscall.
+func withPermissionHint(err error) error {
+	if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+	return fmt.Errorf("%w (check that %s is owned and writable by the current user, e.g. chown -R $USER %s)", err, vsClaudeDir, vsClaudeDir)
+}
+
+// appendCommand appends a command line to the command file. An advisory lock
+// on a sidecar lock file is held around the write and sync, so command lines
+// from concurrent requests or MCP server processes never interleave. Lock
+// acquisition gives up after timeout. The command file is compacted under the
+// same lock, see compactCommandFile.
+func appendCommand(cmdFile string, cmd Command, timeout time.Duration) error {
+	lock := flock.New(cmdFile + ".lock")
+	lockCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	locked, err := lock.TryLockContext(lockCtx, 10*time.Millisecond)
+	if err != nil || !locked {
+		return withPermissionHint(fmt.Errorf("failed to lock command file: %w", err))
+	}
+	defer lock.Unlock()
+
+	cmdBytes, _ := json.Marshal(cmd)
+	compactCommandFile(cmdFile, int64(len(cmdBytes))+1)
+
+	f, err := ipcFS.OpenFile(cmdFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return withPermissionHint(fmt.Errorf("failed to open command file: %w", err))
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s\n", cmdBytes); err != nil {
+		return withPermissionHint(fmt.Errorf("failed to write command: %w", err))
+	}
+
+	// Flush to ensure the command is written immediately
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to flush command: %v", err)
+	}
+	return nil
+}
+
+// commandFileCompactionSize is the size above which the command file is
+// truncated once the extension has read every command in it.
+const commandFileCompactionSize = 1 << 20
+
+// compactCommandFile truncates the command file if it has grown beyond
+// commandFileCompactionSize and the extension has consumed all of it. The
+// extension reads commands in order, so a final response to the last command
+// in the file means nothing is left unread. The extension resets its read
+// offset when the file shrinks below it, which is guaranteed for the next
+// command as long as that command is smaller than the truncated file. Must be
+// called with the command file lock held.
+func compactCommandFile(cmdFile string, nextLineSize int64) {
+	info, err := ipcFS.Stat(cmdFile)
+	if err != nil || info.Size() < commandFileCompactionSize || nextLineSize >= info.Size() {
+		return
+	}
+
+	data, err := ipcFS.ReadFile(cmdFile)
+	if err != nil {
+		return
+	}
+	var last Command
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || last.ID == "" {
+		return
+	}
+	respFile := strings.TrimSuffix(cmdFile, ".in") + ".out"
+	if !hasFinalResponse(respFile, last.ID) {
+		return
+	}
+
+	if err := ipcFS.Truncate(cmdFile, 0); err != nil {
+		logWarnf("Failed to compact command file %s: %v", cmdFile, err)
+		return
+	}
+	logInfof("Compacted command file %s (%d bytes)", cmdFile, info.Size())
+}
+
+// hasFinalResponse reports whether the response file contains a final,
+// non-partial response for the command with the given ID.
+func hasFinalResponse(respFile, id string) bool {
+	data, err := ipcFS.ReadFile(respFile)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.Contains(line, id) {
+			continue
+		}
+		var resp CommandResponse
+		if err := json.Unmarshal([]byte(line), &resp); err == nil && resp.ID == id && !resp.Partial {
+			return true
+		}
+	}
+	return false
+}
+
+// responseFileGracePeriod is how long writeCommand waits for a missing
+// response file to appear before concluding the extension isn't running.
+const responseFileGracePeriod = 2 * time.Second
+
+// maxLoggedLineLength caps how much of a raw response line ends up in logs
+// and error messages.
+const maxLoggedLineLength = 200
+
+// truncate shortens s to at most max bytes, marking that it was cut.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "... (truncated)"
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExtension is a fileSystem that plays the extension's part on top of
+// the real file system. Whenever a command is appended to a window's command
+// file, it answers by appending the chunks returned by respond to the
+// window's response file: the first one right away, each further one a few
+// poll intervals later, so responses can arrive split across reads.
+type fakeExtension struct {
+	osFileSystem
+	respond func(cmd Command) []string
+	writes  sync.WaitGroup
+}
+
+func (f *fakeExtension) OpenFile(name string, flag int, perm os.FileMode) (writableFile, error) {
+	file, err := f.osFileSystem.OpenFile(name, flag, perm)
+	if err != nil || !strings.HasSuffix(name, ".in") {
+		return file, err
+	}
+	return &commandWriter{writableFile: file, respFile: strings.TrimSuffix(name, ".in") + ".out", extension: f}, nil
+}
+
+// commandWriter hands every command written to a command file to the fake
+// extension.
+type commandWriter struct {
+	writableFile
+	respFile  string
+	extension *fakeExtension
+}
+
+func (w *commandWriter) Write(p []byte) (int, error) {
+	n, err := w.writableFile.Write(p)
+	if err != nil {
+		return n, err
+	}
+	var cmd Command
+	if err := json.Unmarshal(bytes.TrimSpace(p), &cmd); err != nil || w.extension.respond == nil {
+		return n, nil
+	}
+
+	chunks := w.extension.respond(cmd)
+	if len(chunks) == 0 {
+		return n, nil
+	}
+	appendToFile(w.respFile, chunks[0])
+	w.extension.writes.Add(1)
+	go func() {
+		defer w.extension.writes.Done()
+		for _, chunk := range chunks[1:] {
+			time.Sleep(3 * pollInterval)
+			appendToFile(w.respFile, chunk)
+		}
+	}()
+	return n, nil
+}
+
+func appendToFile(name, data string) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		panic(err)
+	}
+}
+
+// setupIPC points the IPC protocol at a temporary directory answered by a
+// fake extension, restoring the real one when the test ends.
+func setupIPC(t *testing.T, respond func(cmd Command) []string) {
+	t.Helper()
+	extension := &fakeExtension{respond: respond}
+	oldDir, oldFS := vsClaudeDir, ipcFS
+	vsClaudeDir, ipcFS = t.TempDir(), extension
+	t.Cleanup(func() {
+		extension.writes.Wait()
+		vsClaudeDir, ipcFS = oldDir, oldFS
+	})
+}
+
+// addWindow writes the files of a running window whose last heartbeat was
+// age ago.
+func addWindow(t *testing.T, windowId, workspace string, age time.Duration) {
+	t.Helper()
+	meta, err := json.Marshal(WindowInfo{WindowID: windowId, Workspace: workspace, WindowTitle: workspace, Timestamp: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metaFile := filepath.Join(vsClaudeDir, windowId+".meta.json")
+	if err := os.WriteFile(metaFile, meta, 0644); err != nil {
+		t.Fatal(err)
+	}
+	heartbeat := time.Now().Add(-age)
+	if err := os.Chtimes(metaFile, heartbeat, heartbeat); err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range []string{".in", ".out"} {
+		if err := os.WriteFile(filepath.Join(vsClaudeDir, windowId+ext), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// responseLine returns a response line as the extension writes it.
+func responseLine(resp CommandResponse) string {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		panic(err)
+	}
+	return string(data) + "\n"
+}
+
+func TestWriteCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(cmd Command) []string
+		timeout time.Duration
+		wantErr string
+		want    CommandResponse
+	}{
+		{
+			name: "success",
+			respond: func(cmd Command) []string {
+				return []string{responseLine(CommandResponse{ID: cmd.ID, Success: true, Data: json.RawMessage(`{"ok":true}`)})}
+			},
+			want: CommandResponse{Success: true, Data: json.RawMessage(`{"ok":true}`)},
+		},
+		{
+			name: "failure with code",
+			respond: func(cmd Command) []string {
+				return []string{responseLine(CommandResponse{ID: cmd.ID, Error: "no such file", Code: "FILE_NOT_FOUND"})}
+			},
+			want: CommandResponse{Error: "no such file", Code: "FILE_NOT_FOUND"},
+		},
+		{
+			name: "responses to other commands are skipped",
+			respond: func(cmd Command) []string {
+				return []string{
+					responseLine(CommandResponse{ID: "other-command", Success: true, Data: json.RawMessage(`"other"`)}) +
+						responseLine(CommandResponse{ID: cmd.ID, Success: true, Data: json.RawMessage(`"ours"`)}),
+				}
+			},
+			want: CommandResponse{Success: true, Data: json.RawMessage(`"ours"`)},
+		},
+		{
+			name: "malformed and empty lines are skipped",
+			respond: func(cmd Command) []string {
+				return []string{"not json\n\n{\"success\": true}\n" + responseLine(CommandResponse{ID: cmd.ID, Success: true})}
+			},
+			want: CommandResponse{Success: true},
+		},
+		{
+			name: "line split across writes",
+			respond: func(cmd Command) []string {
+				line := responseLine(CommandResponse{ID: cmd.ID, Success: true, Data: json.RawMessage(`"split"`)})
+				return []string{line[:10], line[10:20], line[20:]}
+			},
+			want: CommandResponse{Success: true, Data: json.RawMessage(`"split"`)},
+		},
+		{
+			name: "several lines in one write, the last one split",
+			respond: func(cmd Command) []string {
+				other := responseLine(CommandResponse{ID: "other-command", Success: true})
+				ours := responseLine(CommandResponse{ID: cmd.ID, Success: true, Data: json.RawMessage(`1`)})
+				return []string{other + ours[:5], ours[5:]}
+			},
+			want: CommandResponse{Success: true, Data: json.RawMessage(`1`)},
+		},
+		{
+			name: "partial responses are combined",
+			respond: func(cmd Command) []string {
+				return []string{
+					responseLine(CommandResponse{ID: cmd.ID, Success: true, Partial: true, Data: json.RawMessage(`1`)}),
+					responseLine(CommandResponse{ID: cmd.ID, Success: true, Partial: true, Data: json.RawMessage(`2`)}),
+					responseLine(CommandResponse{ID: cmd.ID, Success: true}),
+				}
+			},
+			want: CommandResponse{Success: true, Data: json.RawMessage(`[1,2]`)},
+		},
+		{
+			name:    "timeout without response",
+			respond: func(cmd Command) []string { return nil },
+			timeout: 300 * time.Millisecond,
+			wantErr: "timeout waiting for response to command",
+		},
+		{
+			name: "timeout reports malformed response",
+			respond: func(cmd Command) []string {
+				return []string{fmt.Sprintf(`{"id": %q, "success": tru`+"\n", cmd.ID)}
+			},
+			timeout: 300 * time.Millisecond,
+			wantErr: "received malformed response",
+		},
+		{
+			name: "timeout reports response cut off mid-line",
+			respond: func(cmd Command) []string {
+				return []string{fmt.Sprintf(`{"id": %q, "success": true`, cmd.ID)}
+			},
+			timeout: 300 * time.Millisecond,
+			wantErr: "received malformed response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupIPC(t, tt.respond)
+			addWindow(t, "window-1", "project", 0)
+
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 5 * time.Second
+			}
+			cmd := newCommand("test", json.RawMessage(`{}`))
+			resp, err := writeCommand("window-1", cmd, timeout)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("writeCommand() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("writeCommand() error = %v", err)
+			}
+			if resp.ID != cmd.ID {
+				t.Errorf("ID = %q, want %q", resp.ID, cmd.ID)
+			}
+			if resp.Success != tt.want.Success || resp.Error != tt.want.Error || resp.Code != tt.want.Code {
+				t.Errorf("response = {Success: %v, Error: %q, Code: %q}, want {Success: %v, Error: %q, Code: %q}",
+					resp.Success, resp.Error, resp.Code, tt.want.Success, tt.want.Error, tt.want.Code)
+			}
+			if !bytes.Equal(resp.Data, tt.want.Data) {
+				t.Errorf("Data = %s, want %s", resp.Data, tt.want.Data)
+			}
+		})
+	}
+}
+
+func TestWriteCommandIgnoresEarlierResponses(t *testing.T) {
+	setupIPC(t, func(cmd Command) []string {
+		return []string{responseLine(CommandResponse{ID: cmd.ID, Success: true, Data: json.RawMessage(`"new"`)})}
+	})
+	addWindow(t, "window-1", "project", 0)
+
+	// A response with the same ID written before the command was sent, e.g.
+	// left over from an earlier server process, must not be picked up
+	cmd := newCommand("test", json.RawMessage(`{}`))
+	appendToFile(filepath.Join(vsClaudeDir, "window-1.out"), responseLine(CommandResponse{ID: cmd.ID, Success: true, Data: json.RawMessage(`"old"`)}))
+
+	resp, err := writeCommand("window-1", cmd, 5*time.Second)
+	if err != nil {
+		t.Fatalf("writeCommand() error = %v", err)
+	}
+	if string(resp.Data) != `"new"` {
+		t.Errorf("Data = %s, want \"new\"", resp.Data)
+	}
+}
+
+func TestWriteCommandConcurrent(t *testing.T) {
+	// Answer every command a few poll intervals later, so the commands wait
+	// side by side and their responses interleave
+	setupIPC(t, func(cmd Command) []string {
+		return []string{"", responseLine(CommandResponse{ID: cmd.ID, Success: true, Data: json.RawMessage(fmt.Sprintf("%q", cmd.ID))})}
+	})
+	addWindow(t, "window-1", "project", 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := newCommand("test", json.RawMessage(`{}`))
+			resp, err := writeCommand("window-1", cmd, 5*time.Second)
+			if err != nil {
+				t.Errorf("writeCommand() error = %v", err)
+				return
+			}
+			if want := fmt.Sprintf("%q", cmd.ID); string(resp.Data) != want {
+				t.Errorf("Data = %s, want %s", resp.Data, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestResponseFileCompaction(t *testing.T) {
+	setupIPC(t, func(cmd Command) []string {
+		return []string{responseLine(CommandResponse{ID: cmd.ID, Success: true})}
+	})
+	addWindow(t, "window-1", "project", 0)
+	cmdFile := filepath.Join(vsClaudeDir, "window-1.in")
+	respFile := filepath.Join(vsClaudeDir, "window-1.out")
+
+	// Fill the response file with answers to a command that was served long ago
+	old := newCommand("test", json.RawMessage(`{}`))
+	cmdLine, _ := json.Marshal(old)
+	appendToFile(cmdFile, string(cmdLine)+"\n")
+	line := responseLine(CommandResponse{ID: old.ID, Success: true, Data: json.RawMessage(`"` + strings.Repeat("x", 1000) + `"`)})
+	appendToFile(respFile, strings.Repeat(line, responseFileCompactionSize/len(line)+1))
+
+	// Not compacted while the response file may still be read by others
+	if _, err := writeCommand("window-1", newCommand("test", json.RawMessage(`{}`)), 5*time.Second); err != nil {
+		t.Fatalf("writeCommand() error = %v", err)
+	}
+	if info, _ := os.Stat(respFile); info.Size() < responseFileCompactionSize {
+		t.Fatalf("response file compacted during quiet period, size = %d", info.Size())
+	}
+
+	quiet := time.Now().Add(-2 * responseFileQuietPeriod)
+	if err := os.Chtimes(respFile, quiet, quiet); err != nil {
+		t.Fatal(err)
+	}
+	cmd := newCommand("test", json.RawMessage(`{}`))
+	resp, err := writeCommand("window-1", cmd, 5*time.Second)
+	if err != nil {
+		t.Fatalf("writeCommand() error = %v", err)
+	}
+	if resp.ID != cmd.ID {
+		t.Errorf("ID = %q, want %q", resp.ID, cmd.ID)
+	}
+	if info, _ := os.Stat(respFile); info.Size() >= responseFileCompactionSize {
+		t.Errorf("response file not compacted, size = %d", info.Size())
+	}
+}
+
+func TestCommandFileCompaction(t *testing.T) {
+	setupIPC(t, func(cmd Command) []string {
+		return []string{responseLine(CommandResponse{ID: cmd.ID, Success: true})}
+	})
+	addWindow(t, "window-1", "project", 0)
+	cmdFile := filepath.Join(vsClaudeDir, "window-1.in")
+	respFile := filepath.Join(vsClaudeDir, "window-1.out")
+	size := func() int64 {
+		info, err := os.Stat(cmdFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info.Size()
+	}
+
+	// Fill the command file with commands, the last of which the extension
+	// has only answered partially so far
+	padding := json.RawMessage(`"` + strings.Repeat("x", 1000) + `"`)
+	var pending Command
+	for written := 0; written <= commandFileCompactionSize; {
+		pending = newCommand("test", padding)
+		line, _ := json.Marshal(pending)
+		appendToFile(cmdFile, string(line)+"\n")
+		written += len(line) + 1
+	}
+	appendToFile(respFile, responseLine(CommandResponse{ID: pending.ID, Success: true, Partial: true}))
+
+	before := size()
+	if _, err := writeCommand("window-1", newCommand("test", json.RawMessage(`{}`)), 5*time.Second); err != nil {
+		t.Fatalf("writeCommand() error = %v", err)
+	}
+	if size() <= before {
+		t.Fatalf("command file compacted while its last command had no final response, size = %d", size())
+	}
+
+	// The command just written has its final response, but the next one is
+	// larger than the file: truncating would leave the extension's read
+	// offset inside the new command, so the file is left alone
+	before = size()
+	huge := newCommand("test", json.RawMessage(`"`+strings.Repeat("x", int(before))+`"`))
+	if _, err := writeCommand("window-1", huge, 5*time.Second); err != nil {
+		t.Fatalf("writeCommand() error = %v", err)
+	}
+	if size() <= before {
+		t.Fatalf("command file compacted before a command larger than it, size = %d", size())
+	}
+
+	// Now the last command is answered and the next one is small
+	cmd := newCommand("test", json.RawMessage(`{}`))
+	if _, err := writeCommand("window-1", cmd, 5*time.Second); err != nil {
+		t.Fatalf("writeCommand() error = %v", err)
+	}
+	data, err := os.ReadFile(cmdFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, _ := json.Marshal(cmd)
+	if string(data) != string(line)+"\n" {
+		t.Errorf("command file = %d bytes, want only the last command", len(data))
+	}
+}
+
+func TestWindowQueue(t *testing.T) {
+	tests := []struct {
+		queue   string
+		wantErr error
+	}{
+		{queue: "wait"},
+		{queue: "busy", wantErr: errWindowBusy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.queue, func(t *testing.T) {
+			oldQueue := windowQueue
+			windowQueue = tt.queue
+			t.Cleanup(func() { windowQueue = oldQueue })
+
+			// Answer a few poll intervals later, so the first command is
+			// still in flight when the second one is sent. Note how many
+			// commands were answered whenever one arrives
+			var answeredOnArrival []int
+			setupIPC(t, func(cmd Command) []string {
+				data, _ := os.ReadFile(filepath.Join(vsClaudeDir, "window-1.out"))
+				answeredOnArrival = append(answeredOnArrival, strings.Count(string(data), "\n"))
+				return []string{"", responseLine(CommandResponse{ID: cmd.ID, Success: true})}
+			})
+			addWindow(t, "window-1", "project", 0)
+
+			first := newCommand("test", json.RawMessage(`{}`))
+			done := make(chan error)
+			go func() {
+				_, err := writeCommand("window-1", first, 5*time.Second)
+				done <- err
+			}()
+			for !hasPendingCommands("window-1") {
+				time.Sleep(time.Millisecond)
+			}
+
+			second := newCommand("test", json.RawMessage(`{}`))
+			_, err := writeCommand("window-1", second, 5*time.Second)
+			if err := <-done; err != nil {
+				t.Fatalf("first writeCommand() error = %v", err)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("second writeCommand() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("second writeCommand() error = %v", err)
+			}
+			if want := []int{0, 1}; fmt.Sprint(answeredOnArrival) != fmt.Sprint(want) {
+				t.Errorf("answered commands when each command arrived = %v, want %v", answeredOnArrival, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// logLevel is a stderr log verbosity, lower levels are more verbose.
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+// logLevelNames are the names of the levels, indexed by level.
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+func (l logLevel) String() string {
+	if l < 0 || int(l) >= len(logLevelNames) {
+		return fmt.Sprintf("LEVEL%d", int(l))
+	}
+	return strings.ToUpper(logLevelNames[l])
+}
+
+// minLogLevel is the least severe level written to stderr. Override with
+// VS_CLAUDE_LOG_LEVEL, one of debug, info, warn, error.
+var minLogLevel = envLogLevel("VS_CLAUDE_LOG_LEVEL", levelInfo)
+
+// envLogLevel reads a log level from the given environment variable,
+// falling back to the default if unset or invalid.
+func envLogLevel(name string, defaultValue logLevel) logLevel {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+	for level, levelName := range logLevelNames {
+		if strings.EqualFold(value, levelName) {
+			return logLevel(level)
+		}
+	}
+	log.Printf("[WARN] Ignoring invalid %s=%q, using default %s", name, value, logLevelNames[defaultValue])
+	return defaultValue
+}
+
+func logf(level logLevel, format string, args ...any) {
+	if level < minLogLevel {
+		return
+	}
+	log.Printf("[%s] %s", level, fmt.Sprintf(format, args...))
+}
+
+// logDebugf logs full command and response bodies and other detail only
+// needed when debugging the protocol.
+func logDebugf(format string, args ...any) { logf(levelDebug, format, args...) }
+
+// logInfof logs normal operation, like startup and commands being handled.
+func logInfof(format string, args ...any) { logf(levelInfo, format, args...) }
+
+// logWarnf logs recoverable problems, like ignored settings or bad responses.
+func logWarnf(format string, args ...any) { logf(levelWarn, format, args...) }
+
+// logErrorf logs failures the user has to act on.
+func logErrorf(format string, args ...any) { logf(levelError, format, args...) }
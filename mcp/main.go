@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
-	"time"
+	"syscall"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -19,20 +23,39 @@ var vsClaudeDir = filepath.Join(os.Getenv("HOME"), ".vs-claude")
 // Common description suffix for all tools about windowId
 const windowIdNote = `
 
-Note: When multiple VS Code windows are open, the tool will return an error listing available windows. 
-Pass the windowId at the top level of your request to specify which window to use:
-{"args": {...}, "windowId": "window-123"}`
+Note: When multiple VS Code windows are open, the tool will return an error listing available windows,
+unless a default window was set with setDefaultWindow. Pass the windowId at the top level of your request to specify which window to use:
+{"args": {...}, "windowId": "window-123"}
+Or pass "allWindows": true instead to run the command in every window; the result is then a JSON
+array of {"windowId", "success", "data", "error"} entries, one per window.
+Pass "resolveRelative": true to allow paths relative to the window's workspace folders, and
+"relativePaths": true to get paths in the result relative to them as {"roots": [...], "result": ...}.`
 
-type WindowInfo struct {
-	Workspace   string    `json:"workspace"`
-	WindowTitle string    `json:"windowTitle"`
-	Timestamp   time.Time `json:"timestamp"`
-}
+const dryRunNote = `
+
+Dry run: pass "dryRun": true at the top level to preview the change without applying it. The result
+is then {"dryRun": true, "changes": [{"path": "/path/to/file.ts", "edits": [{"range": {"startLine": 3,
+"startCharacter": 0, "endLine": 3, "endCharacter": 8}, "newText": "..."}]}, ...]}; created, moved, and
+deleted files are listed as {"path": ..., "operation": "create" | "rename" | "delete", "to": ...}.`
 
 type Command struct {
 	ID   string          `json:"id"`
 	Tool string          `json:"tool"`
 	Args json.RawMessage `json:"args"`
+	// DryRun asks the extension to report the changes instead of applying them
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// dryRunTools are the editing tools that can preview their changes. Other
+// tools that change state reject dryRun rather than silently applying it.
+var dryRunTools = map[string]bool{
+	"open":            true,
+	"applyEdit":       true,
+	"rename":          true,
+	"formatDocument":  true,
+	"organizeImports": true,
+	"codeActions":     true,
+	"moveFile":        true,
 }
 
 type CommandResponse struct {
@@ -40,61 +63,51 @@ type CommandResponse struct {
 	Success bool            `json:"success"`
 	Data    json.RawMessage `json:"data,omitempty"`
 	Error   string          `json:"error,omitempty"`
+
+	// Code optionally classifies an error, e.g. FILE_NOT_FOUND,
+	// WINDOW_BUSY, or UNSUPPORTED_TYPE, so clients can branch on it
+	Code string `json:"code,omitempty"`
+
+	// Partial marks an intermediate progress line, the command is still
+	// running and a final non-partial response will follow
+	Partial bool `json:"partial,omitempty"`
 }
 
 func main() {
 	// Set up logging to stderr
 	log.SetOutput(os.Stderr)
-	log.Println("VS Claude MCP server starting...")
+	logInfof("VS Claude MCP server starting...")
+
+	// Surface an unusable IPC directory up front rather than on the first command
+	if err := checkVsClaudeDir(); err != nil {
+		logErrorf("%v", err)
+	}
 
 	// Create MCP server
 	mcpServer := server.NewMCPServer(
 		"vs-claude",
-		"1.0.0",
+		serverVersion,
 		server.WithToolCapabilities(true),
 	)
 
-	// Register open tool
-	mcpServer.AddTool(
-		mcp.NewTool("open",
-			mcp.WithDescription(`Open files and diffs in VS Code.
-
-Basic usage:
-- Single item: {"type": "file", "path": "/path/to/file.ts"}
-- Multiple items: [{"type": "file", "path": "/a.ts"}, {"type": "diff", "left": "/b.ts", "right": "/c.ts"}]
-
-File examples:
-- Open file: {"type": "file", "path": "/Users/name/project/src/index.ts"}
-- With line range: {"type": "file", "path": "/path/to/file.ts", "startLine": 10, "endLine": 20}
-- Single line: {"type": "file", "path": "/path/to/file.ts", "startLine": 42}
-- Preview mode: {"type": "file", "path": "/path/to/README.md", "preview": true}
-
-Diff examples:
-- Compare files: {"type": "diff", "left": "/path/to/old.ts", "right": "/path/to/new.ts"}
-- With title: {"type": "diff", "left": "/a.ts", "right": "/b.ts", "title": "Custom Title"}
-
-Git diff examples:
-- Working changes: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD", "to": "working"}
-- Staged changes: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD", "to": "staged"}
-- Last commit: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD~1", "to": "HEAD"}
-- Branch diff: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "main", "to": "feature-branch"}
-- With context: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD", "to": "working", "context": 10}
-
-Notes:
-- All paths must be absolute
-- startLine/endLine are optional and 1-based
-- Git diff works even if file doesn't exist in one revision (shows as added/deleted)`+windowIdNote),
-			mcp.WithObject("files", mcp.Description("File(s) to open - can be a single object or array of objects"), mcp.Required(), mcp.AdditionalProperties(true)),
-			mcp.WithString("windowId", mcp.Description("Optional window ID when multiple VS Code windows are open")),
-		),
-		handleTool,
-	)
+	registerTools(mcpServer)
+
+	// Shut down gracefully on SIGINT/SIGTERM: give in-flight commands a moment
+	// to finish, then fail the remaining ones so the server can exit
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		logInfof("Shutdown requested")
+		shutdownCommands()
+	}()
+
 	// Start serving
-	log.Println("Starting MCP server...")
-	if err := server.ServeStdio(mcpServer); err != nil {
+	logInfof("Starting MCP server...")
+	if err := server.NewStdioServer(mcpServer).Listen(ctx, os.Stdin, os.Stdout); err != nil {
 		// Check if it's a context canceled error (expected when client closes connection)
 		if err.Error() == "context canceled" {
-			log.Println("MCP server shutdown (client disconnected)")
+			logInfof("MCP server shutdown (client disconnected)")
 		} else {
 			log.Fatalf("Server error: %v", err)
 		}
@@ -109,52 +122,126 @@ func handleTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTool
 	// Get all arguments
 	args := request.GetArguments()
 
-	// Check if there's a windowId at the top level
-	windowIdInterface, hasWindowId := args["windowId"]
-	var windowIdStr string
-	if hasWindowId {
-		windowIdStr, _ = windowIdInterface.(string)
+	// Check if there's a windowId or allWindows at the top level
+	windowIdStr := windowIdArg(args)
+	allWindows, err := allWindowsArg(args)
+	if err != nil {
+		return nil, err
+	}
+	resolveRelative, err := resolveRelativeArg(args, allWindows)
+	if err != nil {
+		return nil, err
+	}
+	relativePaths, err := relativePathsArg(args, allWindows)
+	if err != nil {
+		return nil, err
+	}
+	dryRun, err := dryRunArg(toolName, args, allWindows)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get the files argument (no more nested args!)
-	actualArgs, ok := args["files"]
-	if !ok {
-		return nil, fmt.Errorf("missing 'files' parameter")
+	// Relative paths need the target window's folders, so pick it up front
+	var windowId string
+	var folders []string
+	if resolveRelative || relativePaths {
+		var window *WindowInfo
+		windowId, window, err = getTargetWindowInfo(ctx, &windowIdStr)
+		if err != nil {
+			return nil, err
+		}
+		folders = window.Folders
+	}
+	if resolveRelative {
+		if err := resolveRelativePaths(toolName, args, folders); err != nil {
+			return nil, err
+		}
 	}
 
-	// Get the target window
-	windowId, err := getTargetWindow(&windowIdStr)
+	// Validate arguments before contacting the extension
+	if err := validateSchema(toolName, args); err != nil {
+		return nil, err
+	}
+	if err := validateToolArgs(toolName, args); err != nil {
+		return nil, err
+	}
+
+	// Get the arguments to forward to the extension
+	actualArgs, err := toolArgs(toolName, args)
 	if err != nil {
 		return nil, err
 	}
 
+	// Reject inline content too large for the command file
+	if err := checkInlineContentSize(toolName, actualArgs); err != nil {
+		return nil, err
+	}
+
 	// Marshal the actual args to pass through (extension expects 'args' field)
 	argsJson, err := json.Marshal(actualArgs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal arguments: %v", err)
 	}
 
-	// Create command
-	cmd := Command{
-		ID:   fmt.Sprintf("%s-%d", toolName, time.Now().UnixNano()),
-		Tool: toolName,
-		Args: argsJson,
+	if allWindows {
+		logInfof("[COMMAND BROADCAST] %s (%d bytes)", toolName, len(argsJson))
+		logDebugf("[COMMAND BROADCAST] %s: %s", toolName, string(argsJson))
+		return broadcastCommand(ctx, toolName, argsJson, timeoutFor(toolName, args))
 	}
 
+	// Get the target window
+	if windowId == "" {
+		windowId, err = getTargetWindow(ctx, &windowIdStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create command
+	cmd := newCommand(toolName, argsJson)
+	cmd.DryRun = dryRun
+
 	// Send command and wait for response
-	log.Printf("[COMMAND SENT] %s: %s", toolName, string(argsJson))
-	response, err := writeCommand(windowId, cmd, 30*time.Second)
+	logInfof("[COMMAND SENT] %s ID: %s (%d bytes)", toolName, cmd.ID, len(argsJson))
+	logDebugf("[COMMAND SENT] %s: %s", toolName, string(argsJson))
+	response, err := streamCommand(windowId, cmd, timeoutFor(toolName, args), progressNotifier(ctx, request))
+	if errors.Is(err, errWindowBusy) {
+		// Report it like an extension error, so clients can retry on the code
+		response, err = &CommandResponse{ID: cmd.ID, Error: err.Error(), Code: "WINDOW_BUSY"}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute %s: %v", toolName, err)
 	}
 
 	// Log the response
-	log.Printf("[RESPONSE RECEIVED] ID: %s, Success: %v", response.ID, response.Success)
+	logInfof("[RESPONSE RECEIVED] ID: %s, Success: %v", response.ID, response.Success)
+	logDebugf("[RESPONSE RECEIVED] ID: %s, Data: %s", response.ID, string(response.Data))
 	if !response.Success {
-		log.Printf("[ERROR] %s", response.Error)
+		logWarnf("%s failed: %s", toolName, response.Error)
 	}
 
 	// Handle response based on success/failure
+	if !response.Success && (response.Code != "" || hasData(response.Data)) {
+		// Return a structured error clients can branch on, keeping partial
+		// results such as the per-item results of a batched open
+		errorResult := map[string]any{"error": response.Error}
+		if response.Code != "" {
+			errorResult["code"] = response.Code
+		}
+		if hasData(response.Data) {
+			errorResult["data"] = response.Data
+		}
+		errorJson, _ := json.Marshal(errorResult)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: string(errorJson),
+				},
+			},
+			IsError: true,
+		}, nil
+	}
 	if !response.Success {
 		// Return error text directly
 		return &mcp.CallToolResult{
@@ -168,6 +255,9 @@ func handleTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTool
 	}
 
 	// Success case - check if data is a JSON string
+	if relativePaths {
+		response.Data = relativizeResult(response.Data, folders)
+	}
 	dataStr := string(response.Data)
 	trimmed := strings.TrimSpace(dataStr)
 
@@ -175,225 +265,182 @@ func handleTool(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallTool
 		// It's a JSON string - unmarshal and return raw
 		var str string
 		if err := json.Unmarshal(response.Data, &str); err == nil {
-			return &mcp.CallToolResult{
-				Content: []mcp.Content{
-					mcp.TextContent{
-						Type: "text",
-						Text: str,
-					},
-				},
-			}, nil
+			dataStr = str
 		}
 	}
 
-	// Not a string or failed to unmarshal - return JSON as-is
+	// Return the string, or other JSON as-is, within the size limit
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			mcp.TextContent{
 				Type: "text",
-				Text: dataStr,
+				Text: limitResponse(dataStr, maxResponseBytes),
 			},
 		},
 	}, nil
 }
 
-func getTargetWindow(windowId *string) (string, error) {
-	windows, err := getActiveWindows()
-	if err != nil {
-		return "", fmt.Errorf("failed to get active windows: %v", err)
-	}
+// hasData reports whether a response carries data besides null.
+func hasData(data json.RawMessage) bool {
+	trimmed := strings.TrimSpace(string(data))
+	return trimmed != "" && trimmed != "null"
+}
 
-	// If windowId specified, use it
-	if windowId != nil && *windowId != "" {
-		if _, exists := windows[*windowId]; exists {
-			return *windowId, nil
-		}
-		return "", fmt.Errorf("window with ID '%s' not found. Active windows: %d", *windowId, len(windows))
+// limitResponse truncates text longer than max bytes at the last line
+// boundary before the limit and notes how much was dropped. A max of 0
+// disables the limit.
+func limitResponse(text string, max int) string {
+	if max <= 0 || len(text) <= max {
+		return text
 	}
-
-	// If only one window, use it
-	if len(windows) == 1 {
-		for id := range windows {
-			return id, nil
+	cut := strings.LastIndexByte(text[:max], '\n') + 1
+	if cut == 0 {
+		// A single huge line, cut it without splitting a UTF-8 sequence
+		cut = max
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
 		}
 	}
-
-	// Multiple windows, need to specify
-	if len(windows) > 1 {
-		var windowList []string
-		for id, info := range windows {
-			windowList = append(windowList, fmt.Sprintf("- %s: %s", id, info.Workspace))
-		}
-		return "", fmt.Errorf("multiple VS Code windows found. Please specify a windowId:\n%s\n\nCall the tool again with the windowId parameter", strings.Join(windowList, "\n"))
+	dropped := text[cut:]
+	droppedLines := strings.Count(dropped, "\n")
+	if !strings.HasSuffix(dropped, "\n") {
+		droppedLines++
 	}
-
-	return "", fmt.Errorf("no VS Code windows found")
+	return fmt.Sprintf("%s\n[truncated: %d bytes (%d lines) dropped, response exceeds VS_CLAUDE_MAX_RESPONSE_BYTES=%d]", text[:cut], len(dropped), droppedLines, max)
 }
 
-func getActiveWindows() (map[string]*WindowInfo, error) {
-	windows := make(map[string]*WindowInfo)
+// progressNotifier returns a callback that forwards partial responses to the
+// client as progress notifications, or nil if the client didn't request
+// progress for this call.
+func progressNotifier(ctx context.Context, request mcp.CallToolRequest) func(*CommandResponse) {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	mcpServer := server.ServerFromContext(ctx)
+	if mcpServer == nil {
+		return nil
+	}
 
-	files, err := os.ReadDir(vsClaudeDir)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return windows, nil
+	token := request.Params.Meta.ProgressToken
+	progress := 0
+	return func(response *CommandResponse) {
+		progress++
+		params := map[string]any{
+			"progressToken": token,
+			"progress":      progress,
+		}
+		// Partial data that is a plain string doubles as a progress message
+		var message string
+		if err := json.Unmarshal(response.Data, &message); err == nil {
+			params["message"] = message
+		}
+		if err := mcpServer.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+			logWarnf("Failed to send progress notification: %v", err)
 		}
-		return nil, err
 	}
+}
 
-	staleThreshold := 5 * time.Second
-	now := time.Now()
-
-	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".meta.json") {
-			windowId := strings.TrimSuffix(file.Name(), ".meta.json")
-			filePath := filepath.Join(vsClaudeDir, file.Name())
-
-			// Check file modification time
-			fileInfo, err := os.Stat(filePath)
-			if err != nil {
-				continue
-			}
-
-			// If file hasn't been touched in the last 5 seconds, it's stale
-			if now.Sub(fileInfo.ModTime()) > staleThreshold {
-				// Clean up stale window files
-				os.Remove(filePath)
-				cmdFile := filepath.Join(vsClaudeDir, windowId+".in")
-				os.Remove(cmdFile)
-				respFile := filepath.Join(vsClaudeDir, windowId+".out")
-				os.Remove(respFile)
-				log.Printf("Cleaned up stale window: %s", windowId)
-				continue
-			}
-
-			// Read window metadata
-			data, err := os.ReadFile(filePath)
-			if err != nil {
-				continue
-			}
-
-			var info WindowInfo
-			if err := json.Unmarshal(data, &info); err != nil {
-				continue
-			}
+// windowIdArg returns the optional top-level windowId argument.
+func windowIdArg(args map[string]any) string {
+	windowId, _ := args["windowId"].(string)
+	return windowId
+}
 
-			windows[windowId] = &info
-		}
+// newCommand creates a command for the given tool. The ID is a random UUID
+// prefixed with the tool name for log readability, so responses can always
+// be matched unambiguously, even for concurrent calls of the same tool.
+func newCommand(toolName string, argsJson json.RawMessage) Command {
+	return Command{
+		ID:   fmt.Sprintf("%s-%s", toolName, uuid.NewString()),
+		Tool: toolName,
+		Args: argsJson,
 	}
-
-	return windows, nil
 }
 
-// writeCommand writes a command and waits for a response with 30s timeout
-func writeCommand(windowId string, cmd Command, timeout time.Duration) (*CommandResponse, error) {
-	// Write the command
-	cmdFile := filepath.Join(vsClaudeDir, fmt.Sprintf("%s.in", windowId))
+// toolDefaults are argument defaults applied before forwarding, so limits
+// like result caps are enforced even when the caller omits them.
+var toolDefaults = map[string]map[string]any{
+	"showCommands":     {"maxResults": 50},
+	"search":           {"maxResults": 100},
+	"terminal":         {"name": "vs-claude"},
+	"listTodos":        {"tags": []any{"TODO", "FIXME"}, "maxResults": 100},
+	"gitLog":           {"max": 50},
+	"gitRestore":       {"ref": "HEAD"},
+	"watchDiagnostics": {"duration": defaultWatchSeconds},
+}
 
-	f, err := os.OpenFile(cmdFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open command file: %v", err)
+// dryRunArg returns whether the command should only preview its changes.
+// Read-only tools ignore dryRun, since they change nothing anyway.
+func dryRunArg(toolName string, args map[string]any, allWindows bool) (bool, error) {
+	if err := optionalBool(args, "dryRun"); err != nil {
+		return false, err
 	}
-	defer f.Close()
-
-	cmdBytes, _ := json.Marshal(cmd)
-	if _, err := fmt.Fprintf(f, "%s\n", cmdBytes); err != nil {
-		return nil, fmt.Errorf("failed to write command: %v", err)
+	dryRun, _ := args["dryRun"].(bool)
+	if !dryRun || readOnlyTools[toolName] {
+		return false, nil
 	}
-
-	// Flush to ensure the command is written immediately
-	if err := f.Sync(); err != nil {
-		return nil, fmt.Errorf("failed to flush command: %v", err)
+	if !dryRunTools[toolName] {
+		return false, fmt.Errorf("%s does not support dryRun", toolName)
 	}
+	if allWindows {
+		return false, fmt.Errorf("pass either 'dryRun' or 'allWindows', not both")
+	}
+	return true, nil
+}
 
-	// Watch for response
-	respFile := filepath.Join(vsClaudeDir, fmt.Sprintf("%s.out", windowId))
-
-	// Set up timeout
-	deadline := time.Now().Add(timeout)
-
-	// Track last read position and incomplete line buffer
-	var lastPosition int64 = 0
-	var incompleteBuffer string = ""
-
-	// Poll for response every 50ms until timeout
-	for time.Now().Before(deadline) {
-		// Open file to check size and read from last position
-		file, err := os.Open(respFile)
-		if err != nil {
-			if os.IsNotExist(err) {
-				// Response file doesn't exist, extension might not be running
-				time.Sleep(50 * time.Millisecond)
-				continue
-			}
-			return nil, fmt.Errorf("failed to open response file: %v", err)
+// toolArgs extracts the arguments forwarded to the extension. The open tool
+// nests its items under "files", all other tools forward their arguments
+// as-is, minus the top-level windowId and with toolDefaults applied.
+func toolArgs(toolName string, args map[string]any) (any, error) {
+	if toolName == "open" {
+		files, ok := args["files"]
+		if !ok {
+			return nil, fmt.Errorf("missing 'files' parameter")
 		}
+		return files, nil
+	}
 
-		// Get file info to check if there's new data
-		fileInfo, err := file.Stat()
-		if err != nil {
-			file.Close()
-			return nil, fmt.Errorf("failed to stat response file: %v", err)
+	forwarded := make(map[string]any, len(args))
+	for key, value := range args {
+		if key == "windowId" || key == "allWindows" || key == "resolveRelative" || key == "relativePaths" || key == "dryRun" {
+			continue
 		}
+		forwarded[key] = value
+	}
+	// The extension takes 0-based characters, column is the 1-based alternative
+	if column, ok := forwarded["column"].(float64); ok {
+		forwarded["character"] = column - 1
+		delete(forwarded, "column")
+	}
+	for key, value := range toolDefaults[toolName] {
+		if _, ok := forwarded[key]; !ok {
+			forwarded[key] = value
+		}
+	}
+	return forwarded, nil
+}
 
-		// If file has grown, read new data
-		if fileInfo.Size() > lastPosition {
-			// Seek to last read position
-			if _, err := file.Seek(lastPosition, 0); err != nil {
-				file.Close()
-				return nil, fmt.Errorf("failed to seek in response file: %v", err)
-			}
-
-			// Read new data
-			newData := make([]byte, fileInfo.Size()-lastPosition)
-			n, err := file.Read(newData)
-			if err != nil {
-				file.Close()
-				return nil, fmt.Errorf("failed to read response file: %v", err)
-			}
-
-			// Update last position to reflect all bytes read
-			lastPosition += int64(n)
-
-			// Combine with any incomplete buffer from last read
-			dataStr := incompleteBuffer + string(newData)
-			lines := strings.Split(dataStr, "\n")
-
-			// Check if last line is complete
-			if len(lines) > 0 && !strings.HasSuffix(dataStr, "\n") {
-				// Last line is incomplete, save it for next iteration
-				incompleteBuffer = lines[len(lines)-1]
-				lines = lines[:len(lines)-1]
-			} else {
-				// All lines are complete
-				incompleteBuffer = ""
-			}
+// maxInlineContentBytes caps content passed inline with a command, like a
+// diff's rightContent or a created file's content.
+const maxInlineContentBytes = 1 << 20
 
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if line == "" {
-					continue
-				}
-
-				var resp CommandResponse
-				if err := json.Unmarshal([]byte(line), &resp); err != nil {
-					log.Printf("Failed to parse response line: %v", err)
-					continue
-				}
-
-				// Check if this is our response
-				if resp.ID == cmd.ID {
-					file.Close()
-					return &resp, nil
-				}
+// checkInlineContentSize rejects open items whose inline content exceeds
+// maxInlineContentBytes.
+func checkInlineContentSize(toolName string, toolArgs any) error {
+	if toolName != "open" {
+		return nil
+	}
+	items, ok := toolArgs.([]any)
+	if !ok {
+		items = []any{toolArgs}
+	}
+	for i, item := range items {
+		fields, _ := item.(map[string]any)
+		for _, name := range []string{"rightContent", "content"} {
+			if content, ok := fields[name].(string); ok && len(content) > maxInlineContentBytes {
+				return fmt.Errorf("item %d: %s is %d bytes, must not exceed %d bytes (1 MB)", i, name, len(content), maxInlineContentBytes)
 			}
 		}
-
-		file.Close()
-
-		// Wait a bit before next check
-		time.Sleep(50 * time.Millisecond)
 	}
-
-	return nil, fmt.Errorf("timeout waiting for response to command %s", cmd.ID)
+	return nil
 }
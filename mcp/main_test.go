@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimitResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{
+			name: "within the limit",
+			text: "one\ntwo\n",
+			max:  8,
+			want: "one\ntwo\n",
+		},
+		{
+			name: "limit disabled",
+			text: "one\ntwo\n",
+			max:  0,
+			want: "one\ntwo\n",
+		},
+		{
+			name: "cut at the last line boundary",
+			text: "one\ntwo\nthree\n",
+			max:  10,
+			want: "one\ntwo\n\n[truncated: 6 bytes (1 lines) dropped, response exceeds VS_CLAUDE_MAX_RESPONSE_BYTES=10]",
+		},
+		{
+			name: "dropped lines without a final newline",
+			text: "a\nb\nc\nd",
+			max:  3,
+			want: "a\n\n[truncated: 5 bytes (3 lines) dropped, response exceeds VS_CLAUDE_MAX_RESPONSE_BYTES=3]",
+		},
+		{
+			name: "single line cut at a rune boundary",
+			// é is 2 bytes and € 3, the limit falls inside the €
+			text: "aé€b",
+			max:  4,
+			want: "aé\n[truncated: 4 bytes (1 lines) dropped, response exceeds VS_CLAUDE_MAX_RESPONSE_BYTES=4]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limitResponse(tt.text, tt.max); got != tt.want {
+				t.Errorf("limitResponse() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArgsColumn(t *testing.T) {
+	args := map[string]any{"path": "/tmp/a.go", "line": float64(3), "column": float64(5), "windowId": "window-1"}
+	got, err := toolArgs("getBreadcrumbs", args)
+	if err != nil {
+		t.Fatalf("toolArgs() error = %v", err)
+	}
+	want := map[string]any{"path": "/tmp/a.go", "line": float64(3), "character": float64(4)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toolArgs() = %v, want %v", got, want)
+	}
+}
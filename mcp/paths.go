@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// pathFields are the argument and open item fields holding file system
+// paths, which resolveRelative resolves against the workspace folders.
+var pathFields = []string{"path", "left", "right", "repo", "folder"}
+
+// toolPathFields are path fields specific to one tool, whose names mean
+// something else elsewhere, e.g. from/to are git refs in gitDiff items.
+var toolPathFields = map[string][]string{
+	"moveFile": {"from", "to"},
+}
+
+// resolveRelativeArg returns whether relative paths should be resolved
+// against the target window's workspace folders. It can't be combined with
+// allWindows, as every window has its own folders.
+func resolveRelativeArg(args map[string]any, allWindows bool) (bool, error) {
+	if err := optionalBool(args, "resolveRelative"); err != nil {
+		return false, err
+	}
+	resolve, _ := args["resolveRelative"].(bool)
+	if resolve && allWindows {
+		return false, fmt.Errorf("pass either 'resolveRelative' or 'allWindows', not both")
+	}
+	return resolve, nil
+}
+
+// resolveRelativePaths replaces relative paths in the tool arguments, and in
+// the items of the open tool, with absolute paths inside the given workspace
+// folders. Absolute paths are left unchanged.
+func resolveRelativePaths(toolName string, args map[string]any, folders []string) error {
+	fields := append(append([]string{}, pathFields...), toolPathFields[toolName]...)
+	if err := resolvePathFields(args, fields, folders); err != nil {
+		return err
+	}
+
+	if toolName != "open" {
+		return nil
+	}
+	switch files := args["files"].(type) {
+	case map[string]any:
+		return resolvePathFields(files, pathFields, folders)
+	case []any:
+		for i, file := range files {
+			if item, ok := file.(map[string]any); ok {
+				if err := resolvePathFields(item, pathFields, folders); err != nil {
+					return fmt.Errorf("files[%d]: %v", i, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func resolvePathFields(object map[string]any, fields []string, folders []string) error {
+	for _, name := range fields {
+		path, ok := object[name].(string)
+		if !ok {
+			continue
+		}
+		resolved, err := resolveRelativePath(path, folders)
+		if err != nil {
+			return fmt.Errorf("parameter '%s': %v", name, err)
+		}
+		object[name] = resolved
+	}
+
+	// saveAndClose takes a list of paths
+	if paths, ok := object["paths"].([]any); ok {
+		for i, value := range paths {
+			path, ok := value.(string)
+			if !ok {
+				continue
+			}
+			resolved, err := resolveRelativePath(path, folders)
+			if err != nil {
+				return fmt.Errorf("paths[%d]: %v", i, err)
+			}
+			paths[i] = resolved
+		}
+	}
+	return nil
+}
+
+// resolveRelativePath resolves a path relative to one of the workspace
+// folders. With several folders, the one containing the path wins; if more
+// than one contains it, the path is ambiguous.
+func resolveRelativePath(path string, folders []string) (string, error) {
+	if path == "" || filepath.IsAbs(path) {
+		return path, nil
+	}
+	if len(folders) == 0 {
+		return "", fmt.Errorf("can't resolve relative path '%s', the window has no workspace folder open", path)
+	}
+	if len(folders) == 1 {
+		return filepath.Join(folders[0], path), nil
+	}
+
+	var candidates []string
+	for _, folder := range folders {
+		candidate := filepath.Join(folder, path)
+		if _, err := os.Stat(candidate); err == nil {
+			candidates = append(candidates, candidate)
+		}
+	}
+	switch len(candidates) {
+	case 1:
+		return candidates[0], nil
+	case 0:
+		return "", fmt.Errorf("relative path '%s' not found in any workspace folder (%s), pass an absolute path", path, strings.Join(folders, ", "))
+	}
+	return "", fmt.Errorf("relative path '%s' is ambiguous, candidates:\n- %s\nPass one of them as an absolute path", path, strings.Join(candidates, "\n- "))
+}
+
+// relativePathsArg returns whether paths in the result should be made
+// relative to the target window's workspace folders. Like resolveRelative it
+// needs a single target window.
+func relativePathsArg(args map[string]any, allWindows bool) (bool, error) {
+	if err := optionalBool(args, "relativePaths"); err != nil {
+		return false, err
+	}
+	relative, _ := args["relativePaths"].(bool)
+	if relative && allWindows {
+		return false, fmt.Errorf("pass either 'relativePaths' or 'allWindows', not both")
+	}
+	return relative, nil
+}
+
+// relativizeResult rewrites the path fields of a JSON result to be relative
+// to the workspace folder containing them, and wraps it as
+// {"roots": [...], "result": ...} so the folders are only listed once.
+// Results that aren't JSON objects or arrays are returned unchanged.
+func relativizeResult(data json.RawMessage, folders []string) json.RawMessage {
+	trimmed := strings.TrimSpace(string(data))
+	if len(folders) == 0 || trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return data
+	}
+	var result any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return data
+	}
+	wrapped, err := json.Marshal(struct {
+		Roots  []string `json:"roots"`
+		Result any      `json:"result"`
+	}{folders, relativizeValue(result, folders)})
+	if err != nil {
+		return data
+	}
+	return wrapped
+}
+
+func relativizeValue(value any, folders []string) any {
+	switch v := value.(type) {
+	case []any:
+		for i, item := range v {
+			v[i] = relativizeValue(item, folders)
+		}
+	case map[string]any:
+		for key, field := range v {
+			if path, ok := field.(string); ok && slices.Contains(pathFields, key) {
+				v[key] = relativePath(path, folders)
+				continue
+			}
+			v[key] = relativizeValue(field, folders)
+		}
+	}
+	return value
+}
+
+// relativePath returns path relative to the innermost workspace folder
+// containing it, or unchanged if it is outside all of them.
+func relativePath(path string, folders []string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	best, bestRoot := path, ""
+	for _, folder := range folders {
+		rel, err := filepath.Rel(folder, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(folder) > len(bestRoot) {
+			best, bestRoot = rel, folder
+		}
+	}
+	return best
+}
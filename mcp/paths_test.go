@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveRelativePath(t *testing.T) {
+	root := t.TempDir()
+	api, web := filepath.Join(root, "api"), filepath.Join(root, "web")
+	files := []string{filepath.Join(api, "main.go"), filepath.Join(api, "README.md"), filepath.Join(web, "README.md")}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		folders []string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "absolute path unchanged",
+			path:    filepath.Join(web, "index.ts"),
+			folders: []string{api},
+			want:    filepath.Join(web, "index.ts"),
+		},
+		{
+			name:    "single folder joins without checking the file",
+			path:    filepath.Join("src", "new.go"),
+			folders: []string{api},
+			want:    filepath.Join(api, "src", "new.go"),
+		},
+		{
+			name:    "multi-root picks the folder containing the path",
+			path:    "main.go",
+			folders: []string{web, api},
+			want:    filepath.Join(api, "main.go"),
+		},
+		{
+			name:    "multi-root ambiguity",
+			path:    "README.md",
+			folders: []string{api, web},
+			wantErr: "relative path 'README.md' is ambiguous, candidates:\n- " +
+				filepath.Join(api, "README.md") + "\n- " + filepath.Join(web, "README.md"),
+		},
+		{
+			name:    "multi-root not found",
+			path:    "missing.go",
+			folders: []string{api, web},
+			wantErr: "relative path 'missing.go' not found in any workspace folder",
+		},
+		{
+			name:    "no folder open",
+			path:    "main.go",
+			wantErr: "the window has no workspace folder open",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRelativePath(tt.path, tt.folders)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveRelativePath() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveRelativePath() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveRelativePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveRelativePathsInOpenItems(t *testing.T) {
+	folder := t.TempDir()
+	args := map[string]any{"files": []any{
+		map[string]any{"type": "file", "path": "a.go"},
+		map[string]any{"type": "gitDiff", "path": "b.go", "from": "HEAD~1", "to": "working"},
+	}}
+	if err := resolveRelativePaths("open", args, []string{folder}); err != nil {
+		t.Fatalf("resolveRelativePaths() error = %v", err)
+	}
+	want := []any{
+		map[string]any{"type": "file", "path": filepath.Join(folder, "a.go")},
+		map[string]any{"type": "gitDiff", "path": filepath.Join(folder, "b.go"), "from": "HEAD~1", "to": "working"},
+	}
+	if !reflect.DeepEqual(args["files"], want) {
+		t.Errorf("files = %v, want %v, git refs must be left alone", args["files"], want)
+	}
+}
+
+func TestRelativizeResult(t *testing.T) {
+	root := t.TempDir()
+	api, nested := filepath.Join(root, "api"), filepath.Join(root, "api", "vendor")
+	outside := filepath.Join(t.TempDir(), "other.go")
+
+	data, err := json.Marshal(map[string]any{
+		"path":    filepath.Join(api, "main.go"),
+		"summary": filepath.Join(api, "not-a-path-field"),
+		"references": []any{
+			map[string]any{"path": filepath.Join(nested, "lib.go")},
+			map[string]any{"path": outside},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got any
+	if err := json.Unmarshal(relativizeResult(data, []string{api, nested}), &got); err != nil {
+		t.Fatalf("relativizeResult() returned invalid JSON: %v", err)
+	}
+	want := map[string]any{
+		"roots": []any{api, nested},
+		"result": map[string]any{
+			"path":    "main.go",
+			"summary": filepath.Join(api, "not-a-path-field"),
+			"references": []any{
+				// The innermost folder wins
+				map[string]any{"path": "lib.go"},
+				map[string]any{"path": outside},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("relativizeResult() = %v, want %v", got, want)
+	}
+
+	for _, unchanged := range []string{`"just text"`, `42`, ``} {
+		if got := string(relativizeResult(json.RawMessage(unchanged), []string{api})); got != unchanged {
+			t.Errorf("relativizeResult(%s) = %s, want it unchanged", unchanged, got)
+		}
+	}
+}
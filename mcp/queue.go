@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errWindowBusy is returned for a command sent while another one is in
+// flight for the same window, if windowQueue is "busy".
+var errWindowBusy = errors.New("window is busy with another command")
+
+// windowSlots holds a slot per window that a command occupies while it is in
+// flight, if windowQueue serializes commands. A buffered channel of size one
+// serves as a mutex that can be waited for with a timeout.
+var windowSlots = struct {
+	sync.Mutex
+	slots map[string]chan struct{}
+}{slots: make(map[string]chan struct{})}
+
+// acquireWindow occupies the window's slot according to windowQueue: for
+// "wait" once the previous command is done, giving up after timeout; for
+// "busy" only if it is free right away, failing with errWindowBusy otherwise.
+// The returned function frees the slot again.
+func acquireWindow(windowId string, timeout time.Duration) (func(), error) {
+	if windowQueue == "off" {
+		return func() {}, nil
+	}
+
+	windowSlots.Lock()
+	slot, ok := windowSlots.slots[windowId]
+	if !ok {
+		slot = make(chan struct{}, 1)
+		windowSlots.slots[windowId] = slot
+	}
+	windowSlots.Unlock()
+	release := func() { <-slot }
+
+	select {
+	case slot <- struct{}{}:
+		return release, nil
+	default:
+	}
+	if windowQueue == "busy" {
+		return nil, fmt.Errorf("%w, try again once it has finished", errWindowBusy)
+	}
+
+	logDebugf("Waiting for window %s to finish its previous command", windowId)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slot <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("timeout after %v waiting for window %s to finish its previous command", timeout, windowId)
+	case <-shutdown:
+		return nil, errShuttingDown
+	}
+}
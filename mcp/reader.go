@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// responseReaders holds the shared reader of each window's response file,
+// keyed by its path.
+var responseReaders = struct {
+	sync.Mutex
+	readers map[string]*responseReader
+}{readers: make(map[string]*responseReader)}
+
+// responseReader follows a window's response file on behalf of all commands
+// waiting for it in this process. Each line is read once, from an offset kept
+// across polls and commands, and handed to the mailbox of the command it
+// answers, so the cost of a command doesn't grow with the file's history or
+// the number of concurrent commands.
+type responseReader struct {
+	sync.Mutex
+	windowId string
+	respFile string
+	offset   int64
+	// incomplete is a trailing line the extension hasn't finished writing
+	incomplete string
+	// lastInfo is the response file as last seen, to notice it being recreated
+	lastInfo  os.FileInfo
+	mailboxes map[string]*mailbox
+}
+
+// mailbox collects the responses to one command until it takes them.
+type mailbox struct {
+	responses []CommandResponse
+	// malformed is the last unparseable line mentioning the command's ID
+	malformed string
+}
+
+// watchResponses registers a command with its window's reader. It must be
+// called before the command is written. If no other command is waiting, the
+// reader starts at the current end of the response file, as responses to
+// the command can only appear after it.
+func watchResponses(windowId, id string) *responseReader {
+	respFile := filepath.Join(vsClaudeDir, windowId+".out")
+	responseReaders.Lock()
+	reader, ok := responseReaders.readers[respFile]
+	if !ok {
+		reader = &responseReader{
+			windowId:  windowId,
+			respFile:  respFile,
+			mailboxes: make(map[string]*mailbox),
+		}
+		responseReaders.readers[respFile] = reader
+	}
+	responseReaders.Unlock()
+
+	reader.Lock()
+	defer reader.Unlock()
+	if len(reader.mailboxes) == 0 {
+		reader.offset, reader.incomplete, reader.lastInfo = 0, "", nil
+		if info, err := ipcFS.Stat(reader.respFile); err == nil {
+			reader.offset, reader.lastInfo = info.Size(), info
+		}
+	}
+	reader.mailboxes[id] = &mailbox{}
+	return reader
+}
+
+// stopWatching unregisters a command, responses to it are dropped from now on.
+func (r *responseReader) stopWatching(id string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.mailboxes, id)
+}
+
+// take returns and clears the responses received for a command so far.
+func (r *responseReader) take(id string) []CommandResponse {
+	r.Lock()
+	defer r.Unlock()
+	box, ok := r.mailboxes[id]
+	if !ok {
+		return nil
+	}
+	responses := box.responses
+	box.responses = nil
+	return responses
+}
+
+// malformed returns the last unparseable response line mentioning the
+// command's ID, including one cut off mid-line that never got its newline.
+func (r *responseReader) malformed(id string) string {
+	r.Lock()
+	defer r.Unlock()
+	if strings.Contains(r.incomplete, id) {
+		return r.incomplete
+	}
+	if box, ok := r.mailboxes[id]; ok {
+		return box.malformed
+	}
+	return ""
+}
+
+// poll reads the lines appended to the response file since the last poll
+// and delivers them to the waiting commands. It returns an error satisfying
+// os.IsNotExist if the response file doesn't exist (yet).
+func (r *responseReader) poll() error {
+	r.Lock()
+	defer r.Unlock()
+
+	file, err := ipcFS.Open(r.respFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return err
+		}
+		return fmt.Errorf("failed to open response file: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat response file: %v", err)
+	}
+
+	// A reloading window truncates or recreates the response file, and so
+	// does compaction. Responses may then be anywhere in it, so rescan it
+	if info.Size() < r.offset || (r.lastInfo != nil && !os.SameFile(r.lastInfo, info)) {
+		logWarnf("Response file for window %s was truncated or recreated, rescanning it", r.windowId)
+		r.offset, r.incomplete = 0, ""
+	}
+	r.lastInfo = info
+	if info.Size() <= r.offset {
+		return nil
+	}
+
+	if _, err := file.Seek(r.offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek in response file: %v", err)
+	}
+	newData := make([]byte, info.Size()-r.offset)
+	n, err := io.ReadFull(file, newData)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read response file: %v", err)
+	}
+	r.offset += int64(n)
+
+	// Combine with the incomplete line of the last poll, and keep the last
+	// line for the next poll if it isn't complete yet
+	lines := strings.Split(r.incomplete+string(newData[:n]), "\n")
+	r.incomplete = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		r.deliver(strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// deliver hands a response line to the mailbox of the command it answers.
+// Lines for commands nobody waits for (duplicates, or answers to commands that
+// timed out or predate a restart) are drained. Must be called with r locked.
+func (r *responseReader) deliver(line string) {
+	if line == "" {
+		return
+	}
+
+	var resp CommandResponse
+	if err := json.Unmarshal([]byte(line), &resp); err != nil {
+		logWarnf("Failed to parse response line: %v", err)
+		for id, box := range r.mailboxes {
+			if strings.Contains(line, id) {
+				box.malformed = line
+			}
+		}
+		return
+	}
+	if resp.ID == "" {
+		logDebugf("Ignoring response line without ID: %s", truncate(line, maxLoggedLineLength))
+		return
+	}
+
+	box, ok := r.mailboxes[resp.ID]
+	if !ok {
+		logDebugf("Ignoring response for command that is not pending: %s", resp.ID)
+		return
+	}
+	box.responses = append(box.responses, resp)
+}
+
+// responseFileCompactionSize is the size beyond which a response file is
+// truncated once every command has been answered.
+const responseFileCompactionSize = 1 << 20
+
+// responseFileQuietPeriod is how long the response file must not have changed
+// before it is truncated, so other MCP server processes sharing the window
+// have read their responses, whatever their poll interval.
+const responseFileQuietPeriod = 2 * maxPollInterval
+
+// compactResponseFile truncates a window's response file if it has grown
+// beyond responseFileCompactionSize and everything in it has been served:
+// no command of this process is waiting, every command in the command file
+// has a final response, and the file has been quiet for
+// responseFileQuietPeriod. The extension appends to the file, so it simply
+// continues at the new end. The command file lock is held throughout, so no
+// command is written meanwhile.
+func compactResponseFile(windowId string) {
+	respFile := filepath.Join(vsClaudeDir, windowId+".out")
+	info, err := ipcFS.Stat(respFile)
+	if err != nil || info.Size() < responseFileCompactionSize || time.Since(info.ModTime()) < responseFileQuietPeriod {
+		return
+	}
+
+	cmdFile := filepath.Join(vsClaudeDir, windowId+".in")
+	lock := flock.New(cmdFile + ".lock")
+	if locked, err := lock.TryLock(); err != nil || !locked {
+		return
+	}
+	defer lock.Unlock()
+
+	if hasPendingCommands(windowId) || !allCommandsAnswered(cmdFile, respFile) {
+		return
+	}
+	if err := ipcFS.Truncate(respFile, 0); err != nil {
+		logWarnf("Failed to compact response file %s: %v", respFile, err)
+		return
+	}
+	logInfof("Compacted response file %s (%d bytes)", respFile, info.Size())
+}
+
+// allCommandsAnswered reports whether every command in the command file has
+// a final response in the response file, and the response file doesn't end
+// in a line that is still being written.
+func allCommandsAnswered(cmdFile, respFile string) bool {
+	responses, err := ipcFS.ReadFile(respFile)
+	if err != nil || (len(responses) > 0 && responses[len(responses)-1] != '\n') {
+		return false
+	}
+	answered := make(map[string]bool)
+	for _, line := range strings.Split(string(responses), "\n") {
+		var resp CommandResponse
+		if err := json.Unmarshal([]byte(line), &resp); err == nil && resp.ID != "" && !resp.Partial {
+			answered[resp.ID] = true
+		}
+	}
+
+	commands, err := ipcFS.ReadFile(cmdFile)
+	if err != nil && !os.IsNotExist(err) {
+		return false
+	}
+	for _, line := range strings.Split(string(commands), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var cmd Command
+		if err := json.Unmarshal([]byte(line), &cmd); err != nil || !answered[cmd.ID] {
+			return false
+		}
+	}
+	return true
+}
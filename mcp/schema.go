@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolSchemas holds the input schema of every registered tool, so arguments
+// can be checked against the same schema MCP clients see.
+var toolSchemas = map[string]mcp.ToolInputSchema{}
+
+// readOnlyTools holds the registered tools annotated as read-only.
+var readOnlyTools = map[string]bool{}
+
+// validateSchema checks tool arguments against the tool's registered input
+// schema: unknown fields, wrong types, enums, numeric bounds, and nested
+// objects and arrays. Errors name the offending field path, e.g.
+// files[1].startLine.
+func validateSchema(toolName string, args map[string]any) error {
+	schema, ok := toolSchemas[toolName]
+	if !ok {
+		return nil
+	}
+	return validateObject("", args, schema.Properties, schema.Required, false)
+}
+
+// validateValue checks a single value against a JSON schema fragment. Only
+// the keywords used by our tool schemas are supported: type, enum, minimum,
+// maximum, items, properties, required, additionalProperties, and oneOf.
+func validateValue(path string, value any, schema map[string]any) error {
+	if alternatives, ok := schema["oneOf"].([]any); ok {
+		return validateOneOf(path, value, alternatives)
+	}
+
+	if typ, ok := schema["type"].(string); ok {
+		if actual := jsonType(value); actual != typ && !(typ == "integer" && actual == "number") {
+			return fmt.Errorf("parameter '%s' must be %s %s, got %s", path, article(typ), typ, actual)
+		}
+		if typ == "integer" {
+			if n := value.(float64); n != float64(int64(n)) {
+				return fmt.Errorf("parameter '%s' must be an integer", path)
+			}
+		}
+	}
+
+	if allowed, ok := schema["enum"]; ok && !enumContains(allowed, value) {
+		return fmt.Errorf("invalid %s %v, must be one of: %s", path, value, enumList(allowed))
+	}
+
+	if n, ok := value.(float64); ok {
+		if min, ok := schemaNumber(schema["minimum"]); ok && n < min {
+			return fmt.Errorf("parameter '%s' must be at least %v, got %v", path, min, n)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && n > max {
+			return fmt.Errorf("parameter '%s' must be at most %v, got %v", path, max, n)
+		}
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateValue(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		if properties, ok := schema["properties"].(map[string]any); ok {
+			additional, _ := schema["additionalProperties"].(bool)
+			if err := validateObject(path, v, properties, schemaStrings(schema["required"]), additional); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateObject checks an object's fields against the given properties.
+// Unless additional is set, fields without a property schema are rejected.
+func validateObject(path string, object map[string]any, properties map[string]any, required []string, additional bool) error {
+	for _, name := range required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("missing '%s' parameter", joinPath(path, name))
+		}
+	}
+
+	// Check fields in a stable order, so the same call always fails the same way
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := properties[name]
+		if !ok {
+			if additional {
+				continue
+			}
+			known := make([]string, 0, len(properties))
+			for name := range properties {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown parameter '%s', valid parameters: %s", joinPath(path, name), strings.Join(known, ", "))
+		}
+		propertySchema, _ := property.(map[string]any)
+		if err := validateValue(joinPath(path, name), object[name], propertySchema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateOneOf checks a value against a union. Objects are matched by the
+// enum of their "type" field when the alternatives are discriminated that way,
+// otherwise the alternative of the value's JSON type is used, so errors come
+// from the one schema the caller most likely meant.
+func validateOneOf(path string, value any, alternatives []any) error {
+	schemas := make([]map[string]any, 0, len(alternatives))
+	for _, alternative := range alternatives {
+		if schema, ok := alternative.(map[string]any); ok {
+			schemas = append(schemas, schema)
+		}
+	}
+
+	if object, ok := value.(map[string]any); ok {
+		var types []string
+		for _, schema := range schemas {
+			discriminator, ok := schemaProperty(schema, "type")["enum"].([]any)
+			if !ok || len(discriminator) != 1 {
+				continue
+			}
+			typ, _ := discriminator[0].(string)
+			types = append(types, typ)
+			if object["type"] == typ {
+				return validateValue(path, value, schema)
+			}
+		}
+		if len(types) > 0 {
+			sort.Strings(types)
+			if _, ok := object["type"]; !ok {
+				return fmt.Errorf("missing '%s' parameter, valid types: %s", joinPath(path, "type"), strings.Join(types, ", "))
+			}
+			return fmt.Errorf("unknown type '%v' for '%s', valid types: %s", object["type"], joinPath(path, "type"), strings.Join(types, ", "))
+		}
+	}
+
+	var expected []string
+	for _, schema := range schemas {
+		typ, _ := schema["type"].(string)
+		if typ == jsonType(value) {
+			return validateValue(path, value, schema)
+		}
+		expected = append(expected, typ)
+	}
+	return fmt.Errorf("parameter '%s' must be one of: %s, got %s", path, strings.Join(expected, ", "), jsonType(value))
+}
+
+// jsonType returns the JSON schema type name of a decoded JSON value.
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func article(typ string) string {
+	if typ == "integer" || typ == "array" || typ == "object" {
+		return "an"
+	}
+	return "a"
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// schemaProperty returns the schema of an object schema's property.
+func schemaProperty(schema map[string]any, name string) map[string]any {
+	properties, _ := schema["properties"].(map[string]any)
+	property, _ := properties[name].(map[string]any)
+	return property
+}
+
+// enumContains reports whether value is one of the allowed values. Numbers
+// compare by value, whatever Go type the schema declared them with.
+func enumContains(allowed any, value any) bool {
+	for _, a := range schemaValues(allowed) {
+		if an, ok := schemaNumber(a); ok {
+			if vn, ok := value.(float64); ok && an == vn {
+				return true
+			}
+			continue
+		}
+		if a == value {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(allowed any) string {
+	var values []string
+	for _, a := range schemaValues(allowed) {
+		values = append(values, fmt.Sprint(a))
+	}
+	return strings.Join(values, ", ")
+}
+
+// schemaValues returns the values of a schema list. mcp.Enum declares enums
+// as []string, hand-written schemas use []any.
+func schemaValues(list any) []any {
+	switch l := list.(type) {
+	case []any:
+		return l
+	case []string:
+		values := make([]any, len(l))
+		for i, s := range l {
+			values[i] = s
+		}
+		return values
+	}
+	return nil
+}
+
+func schemaStrings(list any) []string {
+	var strs []string
+	for _, v := range schemaValues(list) {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+func schemaNumber(value any) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// rangeSchema is the schema of a {startLine, startCharacter, endLine,
+// endCharacter} range, lines 1-based and characters 0-based.
+var rangeSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"startLine":      map[string]any{"type": "integer", "minimum": 1},
+		"startCharacter": map[string]any{"type": "integer", "minimum": 0},
+		"endLine":        map[string]any{"type": "integer", "minimum": 1},
+		"endCharacter":   map[string]any{"type": "integer", "minimum": 0},
+	},
+	"required": []any{"startLine", "startCharacter", "endLine", "endCharacter"},
+}
+
+// positionSchema is the schema of a {line, character} position, line 1-based
+// and character 0-based.
+var positionSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"line":      map[string]any{"type": "integer", "minimum": 1},
+		"character": map[string]any{"type": "integer", "minimum": 0},
+	},
+	"required": []any{"line", "character"},
+}
+
+// selectionSchema is the schema of a setSelection selection.
+var selectionSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"start": positionSchema,
+		"end":   positionSchema,
+	},
+	"required": []any{"start", "end"},
+}
+
+// textEditSchema is the schema of a single applyEdit edit.
+var textEditSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"range":   rangeSchema,
+		"newText": map[string]any{"type": "string"},
+	},
+	"required": []any{"range", "newText"},
+}
+
+// openItemProperties are the fields of each open item type, besides type.
+var openItemProperties = map[string]map[string]any{
+	"file": {
+		"path":       map[string]any{"type": "string"},
+		"uri":        map[string]any{"type": "string"},
+		"startLine":  map[string]any{"type": "integer", "minimum": 1},
+		"endLine":    map[string]any{"type": "integer", "minimum": 1},
+		"preview":    map[string]any{"type": "boolean"},
+		"newWindow":  map[string]any{"type": "boolean"},
+		"viewColumn": map[string]any{"enum": []any{1, 2, 3, "beside"}},
+		"reveal":     map[string]any{"type": "string", "enum": revealModes},
+		"fold":       map[string]any{"type": "boolean"},
+		"encoding":   map[string]any{"type": "string"},
+	},
+	"diff": {
+		"left":         map[string]any{"type": "string"},
+		"right":        map[string]any{"type": "string"},
+		"rightContent": map[string]any{"type": "string"},
+		"title":        map[string]any{"type": "string"},
+	},
+	"gitDiff": {
+		"path":        map[string]any{"type": "string"},
+		"active":      map[string]any{"type": "boolean"},
+		"from":        map[string]any{"type": "string"},
+		"to":          map[string]any{"type": "string"},
+		"context":     map[string]any{"type": "integer", "minimum": 0},
+		"changedOnly": map[string]any{"type": "boolean"},
+		"maxFiles":    map[string]any{"type": "integer", "minimum": 1},
+	},
+	"insert": {
+		"path":           map[string]any{"type": "string"},
+		"line":           map[string]any{"type": "integer", "minimum": 1},
+		"character":      map[string]any{"type": "integer", "minimum": 0},
+		"text":           map[string]any{"type": "string"},
+		"select":         map[string]any{"type": "boolean"},
+		"eol":            map[string]any{"type": "string", "enum": eolModes},
+		"offsetEncoding": map[string]any{"type": "string", "enum": offsetEncodings},
+	},
+	"notebook": {
+		"path":         map[string]any{"type": "string"},
+		"cell":         map[string]any{"type": "integer", "minimum": 0},
+		"notebookType": map[string]any{"type": "string"},
+	},
+	"reveal": {
+		"path": map[string]any{"type": "string"},
+	},
+	"openFolder": {
+		"path":      map[string]any{"type": "string"},
+		"newWindow": map[string]any{"type": "boolean"},
+	},
+	"createFile": {
+		"path":      map[string]any{"type": "string"},
+		"content":   map[string]any{"type": "string"},
+		"overwrite": map[string]any{"type": "boolean"},
+		"eol":       map[string]any{"type": "string", "enum": eolModes},
+		"bom":       map[string]any{"type": "boolean"},
+	},
+}
+
+// openItemSchema returns the union schema of a single open item, one object
+// schema per type, discriminated by the type field.
+func openItemSchema() map[string]any {
+	alternatives := make([]any, 0, len(openItemProperties))
+	for _, typ := range openItemTypes() {
+		properties := map[string]any{"type": map[string]any{"type": "string", "enum": []any{typ}}}
+		for name, property := range openItemProperties[typ] {
+			properties[name] = property
+		}
+		alternatives = append(alternatives, map[string]any{
+			"type":       "object",
+			"properties": properties,
+			"required":   []any{"type"},
+		})
+	}
+	return map[string]any{"oneOf": alternatives}
+}
+
+// withOpenItems declares the open tool's files parameter: a single open item
+// or an array of them.
+func withOpenItems() mcp.PropertyOption {
+	return func(schema map[string]any) {
+		item := openItemSchema()
+		schema["oneOf"] = []any{
+			map[string]any{"type": "object", "oneOf": item["oneOf"]},
+			map[string]any{"type": "array", "items": item},
+		}
+	}
+}
@@ -0,0 +1,1810 @@
+package main
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// withWindowId adds the optional top-level parameters shared by all tools:
+// windowId, allWindows, resolveRelative, relativePaths, and dryRun.
+func withWindowId() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithString("windowId", mcp.Description("Optional window ID when multiple VS Code windows are open"))(t)
+		mcp.WithBoolean("allWindows", mcp.Description("Send the command to every open VS Code window instead of one"))(t)
+		mcp.WithBoolean("resolveRelative", mcp.Description("Resolve relative paths against the window's workspace folders"))(t)
+		mcp.WithBoolean("relativePaths", mcp.Description("Return paths in the result relative to the window's workspace folders"))(t)
+		mcp.WithBoolean("dryRun", mcp.Description("Preview the changes of an editing tool without applying them, ignored by read-only tools"))(t)
+	}
+}
+
+// withOffsetEncoding adds the optional offsetEncoding parameter of tools that
+// take or return character offsets.
+func withOffsetEncoding() mcp.ToolOption {
+	return mcp.WithString("offsetEncoding",
+		mcp.Description("Unit of the character offsets in the request and result: utf16 (default, VS Code's own), utf8 (bytes), or codepoint"),
+		mcp.Enum(offsetEncodings...))
+}
+
+// withInteger adds an integer parameter. mcp-go only declares numbers, so
+// the type is narrowed once the other options are applied.
+func withInteger(name string, opts ...mcp.PropertyOption) mcp.ToolOption {
+	return mcp.WithNumber(name, append(opts, func(schema map[string]any) {
+		schema["type"] = "integer"
+	})...)
+}
+
+// withEnum restricts a parameter to the given values, which unlike
+// mcp.Enum may be of any JSON type.
+func withEnum(values ...any) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["enum"] = values
+	}
+}
+
+// withOneOf lets a parameter take any of the given schemas, e.g. a string
+// or an object.
+func withOneOf(alternatives ...map[string]any) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		oneOf := make([]any, len(alternatives))
+		for i, alternative := range alternatives {
+			oneOf[i] = alternative
+		}
+		schema["oneOf"] = oneOf
+	}
+}
+
+// withAny adds a parameter that accepts any JSON value.
+func withAny(name string, opts ...mcp.PropertyOption) mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		schema := map[string]any{}
+		for _, opt := range opts {
+			opt(schema)
+		}
+		if required, ok := schema["required"].(bool); ok && required {
+			delete(schema, "required")
+			t.InputSchema.Required = append(t.InputSchema.Required, name)
+		}
+		t.InputSchema.Properties[name] = schema
+	}
+}
+
+// registerTools registers all tools with the MCP server. Every tool is
+// dispatched through handleTool, which forwards it to the target window.
+func registerTools(mcpServer *server.MCPServer) {
+	// addTool registers a tool and records its input schema for validateSchema
+	addTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		toolSchemas[tool.Name] = tool.InputSchema
+		if hint := tool.Annotations.ReadOnlyHint; hint != nil && *hint {
+			readOnlyTools[tool.Name] = true
+		}
+		mcpServer.AddTool(tool, handler)
+	}
+
+	// Register open tool
+	addTool(
+		mcp.NewTool("open",
+			mcp.WithDescription(`Open files, notebooks, and diffs in VS Code, create or insert text into files, reveal them in the Explorer, or open folders.
+
+Basic usage:
+- Single item: {"type": "file", "path": "/path/to/file.ts"}
+- Multiple items: [{"type": "file", "path": "/a.ts"}, {"type": "diff", "left": "/b.ts", "right": "/c.ts"}]
+
+File examples:
+- Open file: {"type": "file", "path": "/Users/name/project/src/index.ts"}
+- With line range: {"type": "file", "path": "/path/to/file.ts", "startLine": 10, "endLine": 20}
+- Single line: {"type": "file", "path": "/path/to/file.ts", "startLine": 42}
+- Preview mode: {"type": "file", "path": "/path/to/README.md", "preview": true}
+- New window: {"type": "file", "path": "/path/to/file.ts", "newWindow": true}
+- Second editor group: {"type": "file", "path": "/path/to/file.ts", "viewColumn": 2}
+- Beside the active editor: {"type": "file", "path": "/path/to/file.ts", "viewColumn": "beside"}
+- Legacy encoding: {"type": "file", "path": "/path/to/legacy.txt", "encoding": "shiftjis"}
+- Scroll range to top, fold the rest: {"type": "file", "path": "/path/to/file.ts", "startLine": 300, "endLine": 320, "reveal": "top", "fold": true}
+- Remote file: {"type": "file", "uri": "vscode-remote://ssh-remote+host/home/user/app.ts", "startLine": 5}
+- Untitled document: {"type": "file", "uri": "untitled:Untitled-1"}
+
+Diff examples:
+- Compare files: {"type": "diff", "left": "/path/to/old.ts", "right": "/path/to/new.ts"}
+- With title: {"type": "diff", "left": "/a.ts", "right": "/b.ts", "title": "Custom Title"}
+- Proposed change: {"type": "diff", "left": "/path/to/file.ts", "rightContent": "...proposed content...", "title": "Proposed"}
+
+Git diff examples:
+- Working changes: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD", "to": "working"}
+- Staged changes: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD", "to": "staged"}
+- Unstaged changes: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "staged", "to": "working"}
+- Last commit: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD~1", "to": "HEAD"}
+- Branch diff: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "main", "to": "feature-branch"}
+- With context: {"type": "gitDiff", "path": "/path/to/file.ts", "from": "HEAD", "to": "working", "context": 10}
+- Active editor against main: {"type": "gitDiff", "active": true, "from": "main", "to": "working"}
+- All changed files: {"type": "gitDiff", "changedOnly": true, "from": "main", "to": "HEAD"}
+- Capped changeset: {"type": "gitDiff", "changedOnly": true, "from": "HEAD~1", "to": "HEAD", "maxFiles": 20}
+
+Insert examples:
+- Insert text: {"type": "insert", "path": "/path/to/file.ts", "line": 10, "character": 0, "text": "// TODO\n"}
+- Insert and select: {"type": "insert", "path": "/path/to/file.ts", "line": 1, "character": 0, "text": "import x from 'x';\n", "select": true}
+- Insert then show: [{"type": "insert", "path": "/a.ts", "line": 5, "character": 0, "text": "..."}, {"type": "file", "path": "/a.ts", "startLine": 5}]
+
+Notebook examples:
+- Open notebook: {"type": "notebook", "path": "/path/to/analysis.ipynb"}
+- Reveal a cell: {"type": "notebook", "path": "/path/to/analysis.ipynb", "cell": 3}
+- Other notebook type: {"type": "notebook", "path": "/path/to/book.md", "notebookType": "quarto-notebook"}
+
+Reveal examples:
+- Show file in Explorer: {"type": "reveal", "path": "/path/to/generated/file.ts"}
+- Show folder in Explorer: {"type": "reveal", "path": "/path/to/generated"}
+
+Folder examples:
+- Open in new window: {"type": "openFolder", "path": "/path/to/new-project", "newWindow": true}
+- Replace current folder: {"type": "openFolder", "path": "/path/to/project", "newWindow": false}
+
+Create file examples:
+- New file: {"type": "createFile", "path": "/path/to/src/new-module.ts", "content": "export const x = 1;\n"}
+- Replace existing: {"type": "createFile", "path": "/path/to/config.json", "content": "{}\n", "overwrite": true}
+- Windows line endings: {"type": "createFile", "path": "/path/to/script.bat", "content": "@echo off\n", "eol": "crlf"}
+
+Notes:
+- All paths must be absolute
+- file accepts a uri instead of path for documents that aren't local files, e.g. untitled:,
+  vscode-remote:, or a virtual file system; it must have a scheme and wins if path is given too
+- startLine/endLine are optional and 1-based; endLine must not be before startLine and requires it.
+  Lines past the end of the file are clamped to its last line, check state.lineCount in the result
+- viewColumn is optional and one of 1, 2, 3, or "beside"; files open in the active editor group by default
+- reveal is optional and one of "center" (default), "top", or "centerIfOutsideViewport"; it controls
+  where startLine/endLine are scrolled to
+- fold: true collapses everything except the regions containing startLine/endLine
+- encoding is optional and a VS Code encoding id like "utf8", "iso88591", "windows1252", or "shiftjis";
+  files open with the configured files.encoding by default
+- newWindow opens the file in a new VS Code window and returns as soon as the open was issued;
+  the new window appears as a separate entry in listWindows shortly after
+- insert line is 1-based, character is 0-based; the document is opened if needed and left unsaved
+- insert returns the resulting document version
+- insert character is in UTF-16 code units like all VS Code positions; pass offsetEncoding "utf8"
+  (bytes) or "codepoint" to give it in another unit, e.g. for lines with emoji
+- openFolder returns immediately with a best-effort success: opening a folder may restart the current
+  window's extension, and a new window only shows up in listWindows once it has started
+- createFile writes content to disk, creating missing parent directories, then opens the file; it fails if
+  the file exists unless overwrite is true. content is limited to 1 MB
+- insert and createFile accept eol: "lf", "crlf", or "auto" (default). auto keeps the document's line
+  endings, or uses the files.eol setting for new files; inserted text is converted to match
+- An existing UTF-8 BOM is preserved; createFile with bom: true writes one for a new file
+- notebook opens the file in the notebook editor; cell is 0-based and selects and scrolls to that cell.
+  Paths must end in .ipynb unless notebookType names the notebook provider to use; it fails if VS
+  Code has no notebook provider for the file
+- reveal highlights a file or folder in the Explorer sidebar without opening it; it fails if the path is outside all workspace folders
+- diff takes either right (a file) or rightContent (in-memory text, shown read-only, max 1 MB)
+- Git diff works even if file doesn't exist in one revision (shows as added/deleted)
+- gitDiff active: true diffs the active editor's file instead of path; it fails if no file editor is active
+- gitDiff from/to accept refs plus "staged" (the index) and "working" (the working tree); comparing
+  against "staged" fails if the file has no staged version
+- Returns a JSON array parallel to files, one {"success": ..., "error": ...} entry per item; gitDiff
+  entries also carry the repository used, e.g. {"success": true, "repository": "/path/to/repo"}
+- file entries also report where the editor landed: {"success": true, "state": {"lineCount": 120,
+  "selections": [{"startLine": 10, "startCharacter": 0, "endLine": 20, "endCharacter": 1}],
+  "visibleRange": {"startLine": 1, "startCharacter": 0, "endLine": 45, "endCharacter": 0}}}
+- Items are opened independently; if any fails, the result is an error {"error": ..., "data": [...]}
+  whose data still reports every item, so the ones that opened need not be retried
+- gitDiff with changedOnly opens a multi-file diff of every file changed between from and to; path is
+  optional and selects the repository. If more than maxFiles (default 50) files changed, it fails with the count
+- In a dry run only insert and createFile items report changes, nothing is opened; an existing file
+  replaced by createFile shows as one edit over its whole text`+dryRunNote+windowIdNote),
+			withAny("files", mcp.Description("File(s) to open - can be a single object or array of objects"), mcp.Required(), withOpenItems()),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register getActiveEditor tool
+	addTool(
+		mcp.NewTool("getActiveEditor",
+			mcp.WithDescription(`Get the file and cursor position of the active editor in VS Code.
+
+Use this to find out what the user is currently looking at.
+
+Example:
+- Get active editor: {}
+
+Returns:
+- {"active": {"path": "/path/to/file.ts", "languageId": "typescript",
+  "selection": {"startLine": 10, "startCharacter": 4, "endLine": 10, "endCharacter": 4},
+  "visibleRange": {"startLine": 1, "endLine": 45}}}
+- {"active": null} if no editor is focused
+
+Notes:
+- Lines are 1-based, characters are 0-based
+- An empty selection (start equals end) is the cursor position`+windowIdNote),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register getConfig tool
+	addTool(
+		mcp.NewTool("getConfig",
+			mcp.WithDescription(`Get the effective value of a VS Code configuration setting.
+
+Resolves user, workspace, workspace folder, and language-specific overrides the same way
+the editor does. Use this to respect project conventions (indentation, formatter) before editing.
+
+Examples:
+- Workspace value: {"key": "editor.tabSize"}
+- Scoped to a file: {"key": "editor.tabSize", "path": "/path/to/file.go"}
+- Formatter: {"key": "editor.defaultFormatter", "path": "/path/to/file.ts"}
+
+Returns:
+- {"key": "editor.tabSize", "value": 4, "scope": "workspaceFolderLanguage"}
+- scope is one of: default, user, workspace, workspaceFolder, or the same with a Language suffix
+- value is null and scope is "none" if the setting is not defined
+
+Notes:
+- All paths must be absolute
+- When a path is given, folder and language-specific overrides for that file are applied`+windowIdNote),
+			mcp.WithString("key", mcp.Description("Full configuration key, e.g. editor.tabSize"), mcp.Required()),
+			mcp.WithString("path", mcp.Description("Optional absolute file path to scope the lookup to")),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register setConfig tool
+	addTool(
+		mcp.NewTool("setConfig",
+			mcp.WithDescription(`Update a VS Code configuration setting.
+
+The target scope must always be given explicitly, so user settings are never changed by accident.
+
+Examples:
+- Workspace setting: {"key": "editor.formatOnSave", "value": true, "target": "workspace"}
+- User setting: {"key": "editor.tabSize", "value": 2, "target": "user"}
+- Reset to default: {"key": "editor.tabSize", "value": null, "target": "workspace"}
+
+Returns:
+- {"key": "editor.formatOnSave", "target": "workspace", "previousValue": false, "value": true}
+
+Notes:
+- target must be "workspace" or "user"
+- A null value removes the setting from the target scope
+- Workspace settings require an open folder or workspace`+windowIdNote),
+			mcp.WithString("key", mcp.Description("Full configuration key, e.g. editor.formatOnSave"), mcp.Required()),
+			withAny("value", mcp.Description("New value for the setting (any JSON value, null removes it)"), mcp.Required()),
+			mcp.WithString("target", mcp.Description("Configuration scope to write to"), mcp.Required(), mcp.Enum("workspace", "user")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register listExtensions tool
+	addTool(
+		mcp.NewTool("listExtensions",
+			mcp.WithDescription(`List the extensions installed in VS Code.
+
+Use this to check whether an extension (e.g. golang.go) is available before relying on its features.
+
+Examples:
+- All extensions: {}
+- By id prefix: {"idPrefix": "golang."}
+- By category: {"category": "Linters"}
+
+Returns:
+- [{"id": "golang.go", "name": "Go", "version": "0.41.0", "enabled": true}, ...]
+
+Notes:
+- idPrefix matching is case-insensitive
+- category matches the categories declared in the extension's package.json`+windowIdNote),
+			mcp.WithString("idPrefix", mcp.Description("Optional extension id prefix to filter by, e.g. golang.")),
+			mcp.WithString("category", mcp.Description("Optional marketplace category to filter by, e.g. Linters")),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register listWindows tool
+	addTool(
+		mcp.NewTool("listWindows",
+			mcp.WithDescription(`List the open VS Code windows.
+
+Use this to pick a windowId up front instead of waiting for a multi-window error.
+
+Example:
+- List windows: {}
+
+Returns:
+- [{"windowId": "window-123", "workspace": "my-project", "windowTitle": "my-project",
+  "timestamp": "2025-07-07T10:00:00Z", "stale": false}, ...]
+- The default window set with setDefaultWindow also has "default": true
+- [] if no windows are open
+
+Notes:
+- timestamp is when the window's extension instance started, it stays the same when
+  workspace folders change
+- stale windows stopped sending heartbeats and can't be targeted`),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleListWindows,
+	)
+
+	// Register backupDiff tool
+	addTool(
+		mcp.NewTool("backupDiff",
+			mcp.WithDescription(`Compare VS Code's hot exit backup of a file with its content on disk.
+
+Use this to recover unsaved work after a crash. If a backup exists, a diff between the file on disk
+and the backup is opened, with the backup's timestamp in the diff title.
+
+Example:
+- Check for a backup: {"path": "/path/to/file.ts"}
+
+Returns:
+- {"backup": true, "timestamp": "2025-07-07T10:00:00Z"} when the diff was opened
+- {"backup": false, "message": "no backup available for /path/to/file.ts"} otherwise
+
+Notes:
+- timestamp is when VS Code last wrote the backup
+- VS Code keeps backups of unsaved files while they are dirty and across restarts with hot exit
+- All paths must be absolute`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file to look up a backup for"), mcp.Required()),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register setDefaultWindow tool
+	addTool(
+		mcp.NewTool("setDefaultWindow",
+			mcp.WithDescription(`Set the window tools use when several windows are open and no windowId is passed.
+
+Use this in a mostly single-window workflow, so an occasional second window doesn't require
+passing windowId to every call.
+
+Examples:
+- Set: {"windowId": "window-123"}
+- Clear: {"clear": true}
+
+Returns:
+- {"defaultWindow": "window-123"}, or {"defaultWindow": null} after clearing
+
+Notes:
+- The window must be active; get its windowId from listWindows, which marks the default window
+  with "default": true
+- An explicit windowId always wins, and a single open window is used regardless of the default
+- Once the default window closes it is ignored, and calls without windowId fail again while
+  several windows are open
+- The default is stored in ~/.vs-claude/default and shared by all MCP server processes`),
+			mcp.WithString("windowId", mcp.Description("ID of the window to use by default")),
+			mcp.WithBoolean("clear", mcp.Description("Remove the default window instead of setting one")),
+		),
+		handleSetDefaultWindow,
+	)
+
+	// Register presentationMode tool
+	addTool(
+		mcp.NewTool("presentationMode",
+			mcp.WithDescription(`Toggle a presentation view for demos and screen sharing.
+
+Enabling turns on Zen mode and optionally increases the editor font size. Disabling leaves
+Zen mode and restores the font size that was active before presentation mode was enabled.
+
+Examples:
+- Enable: {"enabled": true}
+- Enable with larger font: {"enabled": true, "fontSize": 18}
+- Disable and restore: {"enabled": false}
+
+Returns:
+- {"enabled": true, "zenMode": true, "fontSize": 18, "previousFontSize": 13}
+
+Notes:
+- fontSize must be between 6 and 100 and is ignored when disabling
+- The font size is changed in workspace settings, never in user settings`+windowIdNote),
+			mcp.WithBoolean("enabled", mcp.Description("Whether presentation mode should be on or off"), mcp.Required()),
+			mcp.WithNumber("fontSize", mcp.Description("Optional editor font size to use while presenting"), mcp.Min(6), mcp.Max(100)),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register getBreadcrumbs tool
+	addTool(
+		mcp.NewTool("getBreadcrumbs",
+			mcp.WithDescription(`Get the breadcrumb trail (enclosing symbols) at a position in a file.
+
+Use this to see where a position sits structurally without fetching the whole outline.
+
+Example:
+- Symbol path at a position: {"path": "/path/to/user_service.go", "line": 42, "column": 5}
+
+Returns:
+- {"trail": [{"name": "services", "kind": "Namespace"}, {"name": "userServiceImpl", "kind": "Struct"},
+  {"name": "CreateUser", "kind": "Method"}], "text": "services > userServiceImpl > CreateUser"}
+- {"trail": [], "text": ""} if no symbol provider is active for the file
+
+Notes:
+- All paths must be absolute
+- line and column are 1-based; instead of column, a 0-based character can be passed as for the
+  other position tools`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("1-based line number"), mcp.Required(), mcp.Min(1)),
+			withInteger("column", mcp.Description("1-based column in the line"), mcp.Min(1)),
+			withInteger("character", mcp.Description("0-based character offset in the line, instead of column"), mcp.Min(0)),
+			withOffsetEncoding(),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register openScm tool
+	addTool(
+		mcp.NewTool("openScm",
+			mcp.WithDescription(`Focus the Source Control view, optionally selecting a changed file.
+
+Use this in review flows so the user sees a file's change in context.
+
+Examples:
+- Focus Source Control: {}
+- Select a file's change: {"path": "/path/to/file.ts"}
+
+Returns:
+- {"focused": true}
+- {"focused": true, "path": "/path/to/file.ts", "group": "index"} when the file was selected
+- group is "index" (staged), "workingTree" (changes), "untracked", or "merge"
+
+Notes:
+- All paths must be absolute
+- Fails if the file has no pending changes in any repository`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Optional absolute path of a changed file to select")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register fileHistoryDiff tool
+	addTool(
+		mcp.NewTool("fileHistoryDiff",
+			mcp.WithDescription(`Get the commit history of a file and diff any two of its revisions.
+
+Use this to walk a file's evolution: list its commits first, then pick two to compare.
+
+Examples:
+- List history: {"path": "/path/to/file.ts"}
+- Explicit repository: {"path": "/path/to/file.ts", "repo": "/path/to/repo"}
+- Compare revisions: {"path": "/path/to/file.ts", "from": "abc1234", "to": "def5678"}
+
+Returns:
+- {"repo": "/path/to/repo", "commits": [{"hash": "def5678...", "author": "Jane Doe",
+  "date": "2025-07-07T10:00:00Z", "summary": "Fix user lookup"}, ...]}
+- When from/to are given, the diff is opened and the same result is returned
+
+Notes:
+- All paths must be absolute
+- repo defaults to the repository containing path
+- from and to must be given together and must be commits that touched the file`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			mcp.WithString("repo", mcp.Description("Optional absolute path of the git repository")),
+			mcp.WithString("from", mcp.Description("Optional older commit to diff from")),
+			mcp.WithString("to", mcp.Description("Optional newer commit to diff to")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register runScript tool
+	addTool(
+		mcp.NewTool("runScript",
+			mcp.WithDescription(`List and run project scripts in an integrated terminal.
+
+Detects package.json scripts, Makefile targets, and Go main packages in the workspace.
+Without a name the detected scripts are listed, with a name the script is run in a terminal.
+
+Examples:
+- List scripts: {}
+- Run a script: {"name": "test"}
+- Run in a specific folder: {"name": "build", "folder": "/path/to/project/web"}
+
+Returns:
+- {"scripts": [{"name": "test", "source": "package.json", "command": "npm run test", "cwd": "/path/to/project"}, ...]}
+- {"started": true, "name": "test", "command": "npm run test", "cwd": "/path/to/project"} when running
+
+Notes:
+- All paths must be absolute
+- Go main packages are listed by their directory, e.g. "go run ./cmd/server"
+- Fails with the list of detected scripts if the name doesn't exist
+- The script's output is only visible in the terminal, not returned`+windowIdNote),
+			mcp.WithString("name", mcp.Description("Optional name of the script to run")),
+			mcp.WithString("folder", mcp.Description("Optional absolute path of the folder to detect scripts in")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register getDiagnostics tool
+	addTool(
+		mcp.NewTool("getDiagnostics",
+			mcp.WithDescription(`Get the problems (diagnostics) reported for a file.
+
+Defaults to the active editor's file. Filter by source to focus on one linter or language server
+at a time, and by severity to focus on errors.
+
+Examples:
+- Active file: {}
+- Specific file: {"path": "/path/to/file.go"}
+- One source: {"path": "/path/to/file.go", "source": "gopls"}
+- Errors only: {"path": "/path/to/file.ts", "source": "eslint", "severity": "error"}
+
+Returns:
+- {"path": "/path/to/file.go", "sources": ["gopls", "staticcheck"], "diagnostics": [{"severity": "error",
+  "source": "gopls", "message": "undefined: foo", "range": {"startLine": 10, "startCharacter": 4,
+  "endLine": 10, "endCharacter": 7}}, ...]}
+- sources lists every source present in the file, regardless of the filters
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based, characters are 0-based
+- severity is one of: error, warning, information, hint
+- Without filters all diagnostics are returned`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Optional absolute path of the file, defaults to the active editor")),
+			mcp.WithString("source", mcp.Description("Optional diagnostic source to filter by, e.g. gopls or eslint")),
+			mcp.WithString("severity", mcp.Description("Optional severity to filter by"), mcp.Enum(diagnosticSeverities...)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register showCommands tool
+	addTool(
+		mcp.NewTool("showCommands",
+			mcp.WithDescription(`Open the command palette filtered by a query, or list matching commands.
+
+Use the interactive mode to guide the user to an action, or list mode to find command ids
+programmatically, e.g. before running a command.
+
+Examples:
+- Open palette: {"query": "format"}
+- List matches: {"query": "format", "list": true}
+- List more matches: {"query": "git", "list": true, "maxResults": 200}
+
+Returns:
+- {"opened": true, "query": "format"} in interactive mode
+- {"commands": [{"id": "editor.action.formatDocument", "title": "Format Document"}, ...],
+  "total": 12, "truncated": false} in list mode
+
+Notes:
+- query matches command ids and titles, case-insensitive
+- List mode returns at most maxResults commands (default 50, max 500)`+windowIdNote),
+			mcp.WithString("query", mcp.Description("Text to filter commands by")),
+			mcp.WithBoolean("list", mcp.Description("Return matching commands instead of opening the palette")),
+			withInteger("maxResults", mcp.Description("Maximum number of commands to return in list mode"), mcp.Min(1), mcp.Max(500)),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register listCommands tool
+	addTool(
+		mcp.NewTool("listCommands",
+			mcp.WithDescription(`List the IDs of the commands registered in VS Code.
+
+Use this to discover command IDs, e.g. those contributed by an extension. Unlike showCommands'
+list mode, this covers commands without a title that never show up in the command palette.
+
+Examples:
+- Git commands: {"filter": "git."}
+- Including internal commands: {"filter": "editor.action", "includeInternal": true}
+- More results: {"filter": "workbench", "maxResults": 500}
+
+Returns:
+- {"commands": ["git.commit", "git.push", ...], "total": 212, "truncated": true}
+- total counts all matches, commands holds at most maxResults of them in alphabetical order
+
+Notes:
+- filter matches anywhere in the ID, case-insensitive; without it, all commands match
+- Internal commands start with an underscore and are left out unless includeInternal is true
+- At most maxResults commands are returned (default 100, max 1000); narrow the filter
+  rather than raising the limit`+windowIdNote),
+			mcp.WithString("filter", mcp.Description("Text the command IDs must contain")),
+			mcp.WithBoolean("includeInternal", mcp.Description("Include internal commands starting with an underscore")),
+			withInteger("maxResults", mcp.Description("Maximum number of command IDs to return"), mcp.Min(1), mcp.Max(1000)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleListCommands,
+	)
+
+	// Register search tool
+	addTool(
+		mcp.NewTool("search",
+			mcp.WithDescription(`Search the workspace for text or a regular expression.
+
+Uses VS Code's search, scoped to the target window's workspace folders. Files excluded by
+.gitignore and the search.exclude setting are skipped, just like in the editor.
+
+Examples:
+- Plain text: {"query": "CreateUser"}
+- Regex: {"query": "func \\w+User\\(", "regex": true}
+- Limit to Go files: {"query": "TODO", "includeGlob": "**/*.go"}
+- More results: {"query": "logger", "maxResults": 500}
+
+Returns:
+- {"matches": [{"path": "/path/to/user_service.go", "line": 42, "character": 5,
+  "text": "func CreateUser(name string) (*User, error) {"}, ...], "truncated": false}
+- truncated is true when more than maxResults matches were found
+
+Notes:
+- line is 1-based, character is 0-based
+- maxResults defaults to 100, max 1000
+- regex uses JavaScript regular expression syntax`+windowIdNote),
+			mcp.WithString("query", mcp.Description("Text or regular expression to search for"), mcp.Required()),
+			mcp.WithBoolean("regex", mcp.Description("Treat query as a regular expression")),
+			mcp.WithString("includeGlob", mcp.Description("Optional glob of files to include, e.g. **/*.go")),
+			withInteger("maxResults", mcp.Description("Maximum number of matches to return"), mcp.Min(1), mcp.Max(1000)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register resolveImport tool
+	addTool(
+		mcp.NewTool("resolveImport",
+			mcp.WithDescription(`Resolve an import in a file to the file(s) it refers to.
+
+Asks the language server for the definition of the import, so module layout doesn't have to be guessed.
+
+Examples:
+- Relative TypeScript import: {"path": "/path/to/src/index.ts", "importSpec": "./models"}
+- Go package import: {"path": "/path/to/main.go", "importSpec": "github.com/acme/app/models"}
+
+Returns:
+- {"resolved": ["/path/to/src/models/index.ts"], "ambiguous": false}
+- {"resolved": [], "ambiguous": false} if the import couldn't be resolved
+- ambiguous is true when the import resolves to more than one location
+
+Notes:
+- All paths must be absolute
+- importSpec must appear verbatim in an import statement of the file
+- Only works for languages whose extension provides definitions for imports`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file containing the import"), mcp.Required()),
+			mcp.WithString("importSpec", mcp.Description("The import specifier as written in the file"), mcp.Required()),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register getFileContent tool
+	addTool(
+		mcp.NewTool("getFileContent",
+			mcp.WithDescription(`Get a file's content as the user sees it in VS Code.
+
+If the file is open with unsaved changes, the editor buffer is returned, otherwise the content on disk.
+
+Examples:
+- Whole file: {"path": "/path/to/file.ts"}
+- Line range: {"path": "/path/to/file.ts", "startLine": 10, "endLine": 20}
+- From a line to the end: {"path": "/path/to/file.ts", "startLine": 100}
+
+Returns:
+- {"path": "/path/to/file.ts", "source": "buffer", "version": 7, "dirty": true, "lineCount": 240,
+  "content": "..."}
+- source is "buffer" for unsaved editor content, "disk" otherwise; version is null for disk content
+
+Notes:
+- All paths must be absolute
+- startLine/endLine are optional, 1-based, and inclusive`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("startLine", mcp.Description("Optional 1-based first line to return"), mcp.Min(1)),
+			withInteger("endLine", mcp.Description("Optional 1-based last line to return"), mcp.Min(1)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register diagnoseConnection tool
+	addTool(
+		mcp.NewTool("diagnoseConnection",
+			mcp.WithDescription(`Measure the health of the connection to a VS Code window.
+
+Sends a series of pings to the window one after another and reports round-trip latency statistics.
+Use this to quantify slowness or flakiness for bug reports.
+
+Examples:
+- Default 5 pings: {}
+- More samples: {"count": 20}
+
+Returns:
+- {"windowId": "window-123", "count": 5, "succeeded": 5, "minMs": 52.1, "maxMs": 60.3,
+  "avgMs": 55.0, "jitterMs": 3.2, "failures": []}
+- failures lists each ping that failed or exceeded the 2 second per-ping timeout
+
+Notes:
+- count must be between 1 and 50
+- jitter is the mean difference between consecutive round trips`+windowIdNote),
+			withInteger("count", mcp.Description("Number of pings to send (default 5)"), mcp.Min(1), mcp.Max(50)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleDiagnoseConnection,
+	)
+
+	// Register goToDefinition tool
+	addTool(
+		mcp.NewTool("goToDefinition",
+			mcp.WithDescription(`Find the definition(s) of the symbol at a position, like F12 in VS Code.
+
+Examples:
+- Find definition: {"path": "/path/to/main.go", "line": 10, "character": 4}
+- Find and open: {"path": "/path/to/main.go", "line": 10, "character": 4, "open": true}
+- Peek inline: {"path": "/path/to/main.go", "line": 10, "character": 4, "peek": true}
+
+Returns:
+- [{"path": "/path/to/user_service.go", "range": {"startLine": 42, "startCharacter": 5,
+  "endLine": 42, "endCharacter": 15}}, ...]
+- [] if no definition was found
+- With peek: {"peeked": true, "definitions": [...]}, peeked is false if there was nothing to peek
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based, characters are 0-based
+- open shows the first definition in an editor, the same way the open tool would
+- peek shows the definitions in an inline peek view at the position instead of navigating away,
+  so the user keeps their place; it can't be combined with open`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("1-based line number"), mcp.Required(), mcp.Min(1)),
+			withInteger("character", mcp.Description("0-based character offset in the line"), mcp.Required(), mcp.Min(0)),
+			mcp.WithBoolean("open", mcp.Description("Also open the first definition in an editor")),
+			mcp.WithBoolean("peek", mcp.Description("Show the definitions in a peek view at the position instead")),
+			withOffsetEncoding(),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register findReferences tool
+	addTool(
+		mcp.NewTool("findReferences",
+			mcp.WithDescription(`Find all references to the symbol at a position using the language server.
+
+Use this for impact analysis before proposing a rename or refactor.
+
+Examples:
+- All references: {"path": "/path/to/user.go", "line": 12, "character": 6}
+- Without the declaration: {"path": "/path/to/user.go", "line": 12, "character": 6, "includeDeclaration": false}
+- Capped: {"path": "/path/to/user.go", "line": 12, "character": 6, "maxResults": 20}
+- Peek inline: {"path": "/path/to/user.go", "line": 12, "character": 6, "peek": true}
+
+Returns:
+- {"references": [{"path": "/path/to/main.go", "range": {"startLine": 30, "startCharacter": 8,
+  "endLine": 30, "endCharacter": 12}}, ...], "total": 42}
+- total is the number of references found, even if maxResults returned fewer
+- {"references": [], "total": 0} if the symbol has no references
+- With peek the result also has "peeked", false if there were no references to peek
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based, characters are 0-based
+- includeDeclaration defaults to true
+- peek opens the file at the position and shows the references in an inline peek view`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("1-based line number"), mcp.Required(), mcp.Min(1)),
+			withInteger("character", mcp.Description("0-based character offset in the line"), mcp.Required(), mcp.Min(0)),
+			mcp.WithBoolean("includeDeclaration", mcp.Description("Include the symbol's declaration (default true)")),
+			mcp.WithBoolean("peek", mcp.Description("Also show the references in a peek view at the position")),
+			withInteger("maxResults", mcp.Description("Optional maximum number of references to return"), mcp.Min(1)),
+			withOffsetEncoding(),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register showProblems tool
+	addTool(
+		mcp.NewTool("showProblems",
+			mcp.WithDescription(`Reveal the Problems panel so the user sees the issues being discussed.
+
+Nothing else in the window is changed.
+
+Example:
+- Show all problems: {}
+
+Returns:
+- {"shown": true, "counts": {"error": 3, "warning": 12, "information": 0, "hint": 1}}
+- counts cover the whole workspace
+
+Notes:
+- The panel's filters can't be set through the extension API, they stay as the user left them
+- Use getDiagnostics to read problems instead of showing them`+windowIdNote),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register rename tool
+	addTool(
+		mcp.NewTool("rename",
+			mcp.WithDescription(`Rename the symbol at a position across the workspace using the language server.
+
+Safer than search and replace: only real references of the symbol are changed.
+
+Example:
+- Rename: {"path": "/path/to/user.go", "line": 12, "character": 6, "newName": "Account"}
+
+Returns:
+- {"applied": true, "changes": [{"path": "/path/to/user.go", "ranges": [{"startLine": 12,
+  "startCharacter": 5, "endLine": 12, "endCharacter": 9}, ...]}, ...]}
+- If applying fails partway: {"applied": false, "changed": ["/path/a.go"], "unchanged": ["/path/b.go"], "error": "..."}
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based, characters are 0-based
+- Fails with the provider's message if the position isn't a renameable symbol or the new name is rejected
+- Changed files are left unsaved so the user can review them`+dryRunNote+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("1-based line number"), mcp.Required(), mcp.Min(1)),
+			withInteger("character", mcp.Description("0-based character offset in the line"), mcp.Required(), mcp.Min(0)),
+			mcp.WithString("newName", mcp.Description("New name for the symbol"), mcp.Required()),
+			withOffsetEncoding(),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register rulers tool
+	addTool(
+		mcp.NewTool("rulers",
+			mcp.WithDescription(`Show or clear vertical ruler guides in an editor.
+
+Use this to visualize line-length conventions while discussing them. The file is opened if needed.
+Rulers are set in the workspace settings for the file's language, user settings are never changed.
+
+Examples:
+- Show rulers: {"path": "/path/to/file.go", "columns": [80, 120]}
+- Clear rulers: {"path": "/path/to/file.go", "clear": true}
+
+Returns:
+- {"path": "/path/to/file.go", "columns": [80, 120]}
+- columns is [] after clearing
+
+Notes:
+- All paths must be absolute
+- Exactly one of columns or clear must be given
+- Columns are 1-based`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			mcp.WithArray("columns", mcp.Description("Columns to draw rulers at"), mcp.Items(map[string]any{"type": "integer", "minimum": 1})),
+			mcp.WithBoolean("clear", mcp.Description("Remove the rulers instead")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register organizeImports tool
+	addTool(
+		mcp.NewTool("organizeImports",
+			mcp.WithDescription(`Organize the imports of a file using the source.organizeImports code action.
+
+A common post-edit step for Go, TypeScript, and other languages. The file is opened if needed.
+
+Example:
+- Organize imports: {"path": "/path/to/main.go"}
+
+Returns:
+- {"applied": true, "version": 12} if imports were changed
+- {"applied": false, "version": 11} if imports were already organized
+
+Notes:
+- All paths must be absolute
+- Fails if no organize imports provider is available for the file's language
+- The file is left unsaved`+dryRunNote+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register gitStashList tool
+	addTool(
+		mcp.NewTool("gitStashList",
+			mcp.WithDescription(`List the git stash entries of a repository.
+
+Use this to see stashed work before deciding to apply or review it.
+
+Examples:
+- Workspace repository: {}
+- Explicit repository: {"repo": "/path/to/repo"}
+
+Returns:
+- {"repo": "/path/to/repo", "stashes": [{"index": 0, "ref": "stash@{0}", "message": "WIP on main: abc1234 Fix login",
+  "branch": "main", "date": "2025-07-07T10:00:00Z"}, ...]}
+- stashes is [] if there are no stashes
+
+Notes:
+- All paths must be absolute
+- repo defaults to the repository of the first workspace folder`+windowIdNote),
+			mcp.WithString("repo", mcp.Description("Optional absolute path of the git repository")),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register gitStash tool
+	addTool(
+		mcp.NewTool("gitStash",
+			mcp.WithDescription(`Push, apply, pop, or drop a git stash.
+
+Examples:
+- Stash changes: {"action": "push", "message": "WIP login form"}
+- Stash only staged changes: {"action": "push", "staged": true}
+- Include untracked files: {"action": "push", "includeUntracked": true}
+- Apply latest: {"action": "apply"}
+- Pop a specific stash: {"action": "pop", "stash": "stash@{2}"}
+- Drop: {"action": "drop", "stash": "stash@{0}", "confirm": true}
+- Explicit repository: {"action": "apply", "repo": "/path/to/repo"}
+
+Returns:
+- {"repo": "/path/to/repo", "action": "pop", "stashes": [...], "conflicts": ["/path/to/file.ts"]}
+- stashes is the resulting stash list, in the same format as gitStashList
+- conflicts lists files with merge conflicts after apply/pop, [] otherwise
+
+Notes:
+- All paths must be absolute
+- stash defaults to stash@{0} for apply, pop, and drop
+- drop permanently discards the stash and requires confirm: true
+- repo defaults to the repository of the first workspace folder`+windowIdNote),
+			mcp.WithString("action", mcp.Description("Stash operation to perform"), mcp.Required(), mcp.Enum("apply", "pop", "drop", "push")),
+			mcp.WithString("stash", mcp.Description("Stash to apply, pop, or drop (default stash@{0})")),
+			mcp.WithString("message", mcp.Description("Optional message for push")),
+			mcp.WithBoolean("staged", mcp.Description("Only stash staged changes (push)")),
+			mcp.WithBoolean("includeUntracked", mcp.Description("Also stash untracked files (push)")),
+			mcp.WithBoolean("confirm", mcp.Description("Must be true to drop a stash")),
+			mcp.WithString("repo", mcp.Description("Optional absolute path of the git repository")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register showMessage tool
+	addTool(
+		mcp.NewTool("showMessage",
+			mcp.WithDescription(`Show a notification to the user in VS Code, optionally with action buttons.
+
+With actions, the call waits until the user clicks one or dismisses the notification, which makes
+it usable for lightweight confirmations.
+
+Examples:
+- Info: {"message": "Refactoring finished"}
+- Warning: {"kind": "warning", "message": "Tests are failing"}
+- Ask: {"message": "Apply the proposed change?", "actions": ["Apply", "Skip"]}
+
+Returns:
+- {"shown": true, "action": null} without actions or when dismissed
+- {"shown": true, "action": "Apply"} when the user clicked an action
+
+Notes:
+- kind is one of: info (default), warning, error
+- Calls with actions wait up to 5 minutes for the user (VS_CLAUDE_INTERACTIVE_TIMEOUT_MS)`+windowIdNote),
+			mcp.WithString("message", mcp.Description("Message to show"), mcp.Required()),
+			mcp.WithString("kind", mcp.Description("Notification kind (default info)"), mcp.Enum("info", "warning", "error")),
+			mcp.WithArray("actions", mcp.Description("Optional action buttons to offer"), mcp.Items(map[string]any{"type": "string"})),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register openDefinitionBeside tool
+	addTool(
+		mcp.NewTool("openDefinitionBeside",
+			mcp.WithDescription(`Open the definition of the symbol at a position in a split beside the current editor.
+
+The original editor stays in place, so a call site and its definition can be viewed side by side.
+
+Example:
+- Definition beside: {"path": "/path/to/main.go", "line": 10, "column": 5}
+
+Returns:
+- {"source": {"path": "/path/to/main.go", "line": 10, "character": 4},
+  "opened": {"path": "/path/to/user_service.go", "line": 42, "character": 5},
+  "others": [{"path": "/path/to/user_service_mock.go", "line": 12, "character": 5}]}
+- others lists further definitions that were not opened, [] if there was only one
+
+Notes:
+- All paths must be absolute
+- line and column are 1-based; instead of column, a 0-based character can be passed as for the
+  other position tools
+- Locations in the result are reported like the other position tools: lines 1-based, characters
+  0-based
+- Fails if no definition was found`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("1-based line number"), mcp.Required(), mcp.Min(1)),
+			withInteger("column", mcp.Description("1-based column in the line"), mcp.Min(1)),
+			withInteger("character", mcp.Description("0-based character offset in the line, instead of column"), mcp.Min(0)),
+			withOffsetEncoding(),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register terminal tool
+	addTool(
+		mcp.NewTool("terminal",
+			mcp.WithDescription(`Run a shell command in VS Code's integrated terminal.
+
+The command runs in a terminal the user can see. Its output is not returned, the response only
+confirms that the command was sent.
+
+Examples:
+- Run tests: {"command": "go test ./..."}
+- In a directory: {"command": "npm run build", "cwd": "/path/to/project/web"}
+- Named terminal: {"command": "make", "name": "build"}
+- Reuse terminal: {"command": "npm test", "name": "tests", "reuse": true}
+- In the background: {"command": "npm run watch", "show": false}
+
+Returns:
+- {"sent": true, "terminal": "vs-claude", "created": true}
+
+Notes:
+- All paths must be absolute
+- name defaults to "vs-claude"
+- Without reuse a new terminal is created for every call; with reuse an existing terminal
+  with the same name receives the command (created if none exists)
+- show defaults to true and brings the terminal into view
+- cwd is only applied when a new terminal is created`+windowIdNote),
+			mcp.WithString("command", mcp.Description("Shell command to run"), mcp.Required()),
+			mcp.WithString("cwd", mcp.Description("Optional absolute working directory for a new terminal")),
+			mcp.WithString("name", mcp.Description("Terminal name (default vs-claude)")),
+			mcp.WithBoolean("show", mcp.Description("Bring the terminal into view (default true)")),
+			mcp.WithBoolean("reuse", mcp.Description("Send to an existing terminal with the same name")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register getLocationRef tool
+	addTool(
+		mcp.NewTool("getLocationRef",
+			mcp.WithDescription(`Get a compact, stable reference to the active editor's selection.
+
+Use this to cite code in responses. The filesystem form can be reopened with the open tool's
+file type (path, startLine, endLine).
+
+Example:
+- Current selection: {}
+
+Returns:
+- {"file": {"path": "/path/to/repo/src/user.ts", "startLine": 10, "startColumn": 5, "endLine": 12,
+  "endColumn": 2, "ref": "/path/to/repo/src/user.ts:10:5-12:2"},
+  "git": {"repo": "/path/to/repo", "commit": "abc1234...", "path": "src/user.ts",
+  "ref": "abc1234:src/user.ts#L10-L12"}}
+- git is null if the file isn't tracked by git
+- {"file": null, "git": null} if no editor is focused
+
+Notes:
+- Lines and columns are 1-based
+- The git form refers to the HEAD commit, so it only matches the editor if the file has no local changes`+windowIdNote),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register ping tool
+	addTool(
+		mcp.NewTool("ping",
+			mcp.WithDescription(`Check that the VS Code extension is alive and processing commands.
+
+Sends a command without side effects and measures the round trip. Use this to tell whether
+the MCP server, the files in ~/.vs-claude, or the extension is at fault when things hang.
+
+Example:
+- Ping: {}
+
+Returns:
+- {"windowId": "window-123", "latencyMs": 54.2,
+  "extension": {"timestamp": "2025-07-07T10:00:00.000Z", "version": "0.0.3"}}`+windowIdNote),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handlePing,
+	)
+
+	// Register capabilities tool
+	addTool(
+		mcp.NewTool("capabilities",
+			mcp.WithDescription(`Report the versions, tools, and open item types supported by the MCP server and the extension.
+
+Use this to feature-detect, e.g. whether the open tool accepts a given item type, instead of
+trying a call and handling the error.
+
+Example:
+- Get capabilities: {}
+
+Returns:
+- {"server": {"version": "1.0.0", "tools": ["applyEdit", ...], "itemTypes": ["createFile", ...]},
+  "extension": {"windowId": "window-123", "version": "0.0.3", "tools": ["capabilities", "open", "ping"],
+  "itemTypes": ["diff", "file", "gitDiff"]}}
+
+Notes:
+- A tool or item type works end to end only if both the server and the extension list it
+- If the extension can't be asked, e.g. no window is open or it predates this tool, extension
+  only has an error and the server part is still returned`+windowIdNote),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleCapabilities,
+	)
+
+	// Register reloadWindow tool
+	addTool(
+		mcp.NewTool("reloadWindow",
+			mcp.WithDescription(`Reload a VS Code window, e.g. to pick up extension or configuration changes.
+
+Example:
+- Reload: {"windowId": "window-123"}
+
+Returns:
+- {"windowId": "window-123", "action": "reloadWindow", "acknowledged": true}
+- acknowledged is false if the window reloaded before the extension confirmed the request;
+  the reload is best-effort, so this is not an error
+- Failing to send the request to the window is still an error
+
+Notes:
+- The extension restarts with the window, so the windowId becomes invalid afterwards; call
+  listWindows to get the reloaded window's new windowId once it is back
+- Unsaved changes are kept, as with a manual reload
+- Commands still in flight in the window are lost`+windowIdNote),
+			withWindowId(),
+		),
+		handleWindowAction,
+	)
+
+	// Register closeWindow tool
+	addTool(
+		mcp.NewTool("closeWindow",
+			mcp.WithDescription(`Close a VS Code window.
+
+Example:
+- Close: {"windowId": "window-123"}
+
+Returns:
+- {"windowId": "window-123", "action": "closeWindow", "acknowledged": true}
+- acknowledged is false if the window closed before the extension confirmed the request;
+  closing is best-effort, so this is not an error
+- Failing to send the request to the window is still an error
+
+Notes:
+- The windowId is invalid afterwards
+- If the window has unsaved changes, VS Code asks the user whether to save them and the window
+  may stay open
+- Commands still in flight in the window are lost`+windowIdNote),
+			withWindowId(),
+			mcp.WithDestructiveHintAnnotation(true),
+		),
+		handleWindowAction,
+	)
+
+	// Register focusWindow tool
+	addTool(
+		mcp.NewTool("focusWindow",
+			mcp.WithDescription(`Bring a VS Code window to the foreground and focus its active editor.
+
+Use this when working across several windows, so the user sees the window being worked on.
+
+Example:
+- Focus: {"windowId": "window-123"}
+
+Returns:
+- {"focusRequested": true, "raiseRequested": true, "focused": true}
+- raiseRequested is false if this VS Code version has no command to raise its window; only the
+  focus inside the window moves then
+- focused is whether VS Code reports the window as focused right after the request
+
+Notes:
+- Raising is requested from the operating system, which may refuse to raise a window while the user
+  works in another application; focused is then false and the window may only flash in the taskbar`+windowIdNote),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register listTodos tool
+	addTool(
+		mcp.NewTool("listTodos",
+			mcp.WithDescription(`List TODO-style comment tags in the workspace.
+
+Uses VS Code's search, so .gitignore and search.exclude are respected.
+
+Examples:
+- Default tags: {}
+- Go files only: {"include": "**/*.go"}
+- Custom tags: {"tags": ["TODO", "FIXME", "HACK", "XXX"]}
+
+Returns:
+- {"todos": [{"path": "/path/to/user.go", "line": 42, "character": 4, "tag": "TODO",
+  "text": "// TODO: validate email"}, ...], "truncated": false}
+- character is where the tag starts in the line, text is the line without surrounding whitespace;
+  truncated is true when more than maxResults were found
+
+Notes:
+- tags defaults to ["TODO", "FIXME"] and matches whole words, case-sensitive
+- maxResults defaults to 100, max 1000
+- Lines are 1-based, characters are 0-based`+windowIdNote),
+			mcp.WithString("include", mcp.Description("Optional glob of files to include, e.g. **/*.go")),
+			mcp.WithArray("tags", mcp.Description("Comment tags to look for (default TODO, FIXME)"), mcp.Items(map[string]any{"type": "string"})),
+			withInteger("maxResults", mcp.Description("Maximum number of results to return"), mcp.Min(1), mcp.Max(1000)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register gitLog tool
+	addTool(
+		mcp.NewTool("gitLog",
+			mcp.WithDescription(`List recent commits of a repository, optionally only those touching a path.
+
+Use this to reason about history before suggesting a rebase or revert. Open a commit's changes
+with openCommit.
+
+Examples:
+- Recent commits: {}
+- Explicit repository: {"repo": "/path/to/repo", "max": 20}
+- History of a file: {"path": "/path/to/repo/src/user.ts"}
+
+Returns:
+- {"repo": "/path/to/repo", "commits": [{"hash": "abc1234...", "parents": ["def5678..."],
+  "author": "Jane Doe <jane@example.com>", "date": "2025-07-07T10:00:00Z",
+  "message": "Fix user lookup\n\nLonger description..."}, ...]}
+
+Notes:
+- All paths must be absolute
+- max defaults to 50, max 500
+- repo defaults to the repository containing path, or of the first workspace folder`+windowIdNote),
+			mcp.WithString("repo", mcp.Description("Optional absolute path of the git repository")),
+			mcp.WithString("path", mcp.Description("Optional absolute path to limit the history to")),
+			withInteger("max", mcp.Description("Maximum number of commits to return (default 50)"), mcp.Min(1), mcp.Max(500)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register openCommit tool
+	addTool(
+		mcp.NewTool("openCommit",
+			mcp.WithDescription(`Open the full diff of a commit across all files it changed.
+
+Examples:
+- Open commit: {"commit": "abc1234"}
+- Explicit repository: {"commit": "HEAD~2", "repo": "/path/to/repo"}
+
+Returns:
+- {"repo": "/path/to/repo", "commit": "abc1234...", "files": 5}
+
+Notes:
+- All paths must be absolute
+- commit can be any revision git understands (hash, HEAD~1, tag)
+- Merge commits are diffed against their first parent
+- repo defaults to the repository of the first workspace folder`+windowIdNote),
+			mcp.WithString("commit", mcp.Description("Commit to open"), mcp.Required()),
+			mcp.WithString("repo", mcp.Description("Optional absolute path of the git repository")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register gitRestore tool
+	addTool(
+		mcp.NewTool("gitRestore",
+			mcp.WithDescription(`Restore a file's content to a git revision, discarding local changes.
+
+The editor picks up the change and language servers re-analyze the file. Use this to undo an
+unwanted change at file granularity.
+
+Examples:
+- Discard working changes: {"path": "/path/to/repo/src/user.ts", "confirm": true}
+- Restore an older revision: {"path": "/path/to/repo/src/user.ts", "ref": "HEAD~3", "confirm": true}
+- Explicit repository: {"path": "/path/to/repo/src/user.ts", "repo": "/path/to/repo", "confirm": true}
+
+Returns:
+- {"repo": "/path/to/repo", "path": "/path/to/repo/src/user.ts", "ref": "HEAD",
+  "previousHash": "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"}
+- previousHash is the git blob hash of the content on disk before restoring
+
+Notes:
+- All paths must be absolute and path must be inside repo
+- ref defaults to HEAD
+- Requires confirm: true since local changes to the file are lost
+- repo defaults to the repository containing path`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file to restore"), mcp.Required()),
+			mcp.WithString("ref", mcp.Description("Revision to restore from (default HEAD)")),
+			mcp.WithString("repo", mcp.Description("Optional absolute path of the git repository")),
+			mcp.WithBoolean("confirm", mcp.Description("Must be true, local changes to the file are discarded"), mcp.Required()),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register gitBlame tool
+	addTool(
+		mcp.NewTool("gitBlame",
+			mcp.WithDescription(`Get line-level authorship of a file: who last changed each line, when, and in which commit.
+
+Use this to answer "why is this code here" questions, then open the commit with openCommit.
+
+Examples:
+- Line range: {"path": "/path/to/repo/src/user.ts", "startLine": 10, "endLine": 20}
+- Single line: {"path": "/path/to/repo/src/user.ts", "startLine": 42, "endLine": 42}
+- Whole file: {"path": "/path/to/repo/src/user.ts"}
+
+Returns:
+- {"repo": "/path/to/repo", "lines": [{"line": 10, "commit": "abc1234...", "author": "Jane Doe",
+  "date": "2025-07-07T10:00:00Z", "summary": "Fix user lookup"}, ...]}
+
+Notes:
+- All paths must be absolute
+- startLine/endLine are optional, 1-based, and inclusive
+- Blame reflects the file on disk; unsaved editor changes are not included
+- Lines not committed yet have commit "0000000000000000000000000000000000000000"
+- Fails for files not tracked by git`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file to blame"), mcp.Required()),
+			withInteger("startLine", mcp.Description("Optional 1-based first line"), mcp.Min(1)),
+			withInteger("endLine", mcp.Description("Optional 1-based last line (inclusive)"), mcp.Min(1)),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register saveAndClose tool
+	addTool(
+		mcp.NewTool("saveAndClose",
+			mcp.WithDescription(`Save editors with unsaved changes, then close them, without the "save changes?" prompt.
+
+Each file is saved before its editors are closed. If saving fails (e.g. a read-only file), its
+editors stay open and the failure is reported, so nothing is lost and no dialog blocks the session.
+
+Examples:
+- Specific files: {"paths": ["/path/to/file1.ts", "/path/to/file2.ts"]}
+- All editors: {"all": true}
+
+Returns:
+- {"closed": ["/path/to/file1.ts", "/path/to/file2.ts"], "saved": ["/path/to/file2.ts"],
+  "failed": [{"path": "/path/to/readonly.ts", "error": "..."}]}
+- saved lists the files that had unsaved changes and were saved in the process
+
+Notes:
+- All paths must be absolute
+- Pass either paths or all: true
+- Paths without an open editor are ignored`+windowIdNote),
+			mcp.WithArray("paths", mcp.Description("Absolute paths of the files to save and close"), mcp.Items(map[string]any{"type": "string"})),
+			mcp.WithBoolean("all", mcp.Description("Save and close all editors")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register applyEdit tool
+	addTool(
+		mcp.NewTool("applyEdit",
+			mcp.WithDescription(`Apply several text edits to a file in one transaction.
+
+All edits are applied as a single workspace edit: either all of them apply or none do, and they
+produce exactly one new document version. Ranges refer to the document before any edit is applied.
+
+Examples:
+- Replace a word: {"path": "/path/to/file.ts", "edits": [{"range": {"startLine": 10, "startCharacter": 4,
+  "endLine": 10, "endCharacter": 7}, "newText": "user"}]}
+- Insert and delete: {"path": "/path/to/file.ts", "edits": [
+  {"range": {"startLine": 1, "startCharacter": 0, "endLine": 1, "endCharacter": 0}, "newText": "import x from 'x';\n"},
+  {"range": {"startLine": 20, "startCharacter": 0, "endLine": 22, "endCharacter": 0}, "newText": ""}]}
+
+Returns:
+- {"applied": true, "version": 13}
+- applied is false if the editor refused the edit, e.g. because the file is read-only
+
+Notes:
+- path must be absolute
+- Lines are 1-based, characters are 0-based; an empty range inserts newText
+- Overlapping ranges are rejected before anything is applied
+- eol is "lf", "crlf", or "auto" (default, keeps the document's line endings); newText is converted to
+  match, and an existing BOM is preserved
+- The document is opened if needed and left unsaved`+dryRunNote+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file to edit"), mcp.Required()),
+			mcp.WithArray("edits", mcp.Description("Edits to apply, each {range: {startLine, startCharacter, endLine, endCharacter}, newText}"), mcp.Required(), mcp.Items(textEditSchema)),
+			mcp.WithString("eol", mcp.Description("Line endings of the document after the edit (default auto)"), mcp.Enum(eolModes...)),
+			withOffsetEncoding(),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register formatDocument tool
+	addTool(
+		mcp.NewTool("formatDocument",
+			mcp.WithDescription(`Format a file, or a line range of it, with the formatter configured for its language.
+
+Run this after editing so code matches the project's style. The file is opened if needed.
+
+Examples:
+- Whole document: {"path": "/path/to/main.go"}
+- Line range: {"path": "/path/to/main.go", "startLine": 10, "endLine": 40}
+
+Returns:
+- {"formatted": true, "changed": true, "version": 12}
+- {"formatted": false, "changed": false, "message": "No formatter available for language 'plaintext'"}
+
+Notes:
+- All paths must be absolute
+- startLine/endLine are optional, 1-based, and inclusive; endLine defaults to startLine
+- Range formatting needs a range formatter, which some languages don't provide
+- The file is left unsaved`+dryRunNote+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("startLine", mcp.Description("Optional 1-based first line to format"), mcp.Min(1)),
+			withInteger("endLine", mcp.Description("Optional 1-based last line to format (inclusive)"), mcp.Min(1)),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register codeActions tool
+	addTool(
+		mcp.NewTool("codeActions",
+			mcp.WithDescription(`List the code actions (quick fixes and refactors) available at a position, optionally applying one.
+
+Use this to leverage the language's own fixers, like adding a missing import, instead of editing
+by hand. List first, then apply an action by its exact title.
+
+Examples:
+- List actions: {"path": "/path/to/main.go", "line": 10, "character": 4}
+- Apply one: {"path": "/path/to/main.go", "line": 10, "character": 4, "apply": "Add import: \"fmt\""}
+
+Returns:
+- {"actions": [{"title": "Add import: \"fmt\"", "kind": "quickfix", "isPreferred": true}, ...]}
+- With apply: {"applied": "Add import: \"fmt\"", "changedFiles": ["/path/to/main.go"]}
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based, characters are 0-based
+- apply must match a listed title exactly; it fails if no action has that title
+- changedFiles lists the files touched by the action's workspace edit, empty if the action only
+  ran a command
+- Changed files are left unsaved`+dryRunNote+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("1-based line number"), mcp.Required(), mcp.Min(1)),
+			withInteger("character", mcp.Description("0-based character offset in the line"), mcp.Required(), mcp.Min(0)),
+			mcp.WithString("apply", mcp.Description("Optional title of the code action to apply")),
+			withOffsetEncoding(),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register getHover tool
+	addTool(
+		mcp.NewTool("getHover",
+			mcp.WithDescription(`Get the hover information VS Code shows for the symbol at a position.
+
+Gives type signatures and doc comments straight from the language server.
+
+Example:
+- Hover: {"path": "/path/to/main.go", "line": 10, "character": 4}
+
+Returns:
+- The hover contents as markdown text, with multiple hovers separated by blank lines, e.g.
+  "func Println(a ...any) (n int, err error)\n\nPrintln formats using the default formats..."
+- An empty result if there is no hover information at the position
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based, characters are 0-based`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("1-based line number"), mcp.Required(), mcp.Min(1)),
+			withInteger("character", mcp.Description("0-based character offset in the line"), mcp.Required(), mcp.Min(0)),
+			withOffsetEncoding(),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register moveFile tool
+	addTool(
+		mcp.NewTool("moveFile",
+			mcp.WithDescription(`Move or rename a file or folder through VS Code, updating imports that refer to it.
+
+The move runs as a workspace edit, so language extensions that support "update imports on move"
+(e.g. TypeScript, JavaScript) rewrite references in other files, just like dragging the file in
+the Explorer.
+
+Examples:
+- Rename: {"from": "/path/to/src/util.ts", "to": "/path/to/src/string-utils.ts"}
+- Move to another folder: {"from": "/path/to/src/util.ts", "to": "/path/to/src/lib/util.ts"}
+- Replace an existing file: {"from": "/path/to/a.ts", "to": "/path/to/b.ts", "overwrite": true}
+
+Returns:
+- {"moved": true, "from": "/path/to/src/util.ts", "to": "/path/to/src/string-utils.ts",
+  "updatedFiles": ["/path/to/src/main.ts", ...]}
+- updatedFiles lists the files whose imports were rewritten
+
+Notes:
+- All paths must be absolute
+- Fails if to exists unless overwrite is true
+- Missing parent folders of to are created
+- Whether imports are updated depends on the language and its updateImportsOnFileMove setting;
+  rewritten files are left unsaved`+dryRunNote+windowIdNote),
+			mcp.WithString("from", mcp.Description("Absolute path of the file or folder to move"), mcp.Required()),
+			mcp.WithString("to", mcp.Description("Absolute destination path"), mcp.Required()),
+			mcp.WithBoolean("overwrite", mcp.Description("Replace to if it exists")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register watchDiagnostics tool
+	addTool(
+		mcp.NewTool("watchDiagnostics",
+			mcp.WithDescription(`Watch a file's problems (diagnostics) as they change, instead of polling getDiagnostics.
+
+Each change is streamed as a progress notification while the watch runs. Use untilClean after an
+edit to wait until the language server reports no more errors.
+
+Examples:
+- Watch for 30 seconds: {"path": "/path/to/file.go"}
+- Wait until errors are fixed: {"path": "/path/to/file.ts", "severity": "error", "untilClean": true, "duration": 60}
+- One source: {"path": "/path/to/file.go", "source": "gopls", "duration": 10}
+
+Returns:
+- An array of the snapshots seen during the watch, oldest first, each like getDiagnostics returns:
+  [{"path": "/path/to/file.go", "sources": ["gopls"], "diagnostics": [...]}, ...]
+- The first snapshot is the state when the watch started
+
+Notes:
+- All paths must be absolute
+- duration is in seconds, default 30, max 300
+- untilClean ends the watch early once no diagnostics match the filters
+- source and severity filter like in getDiagnostics`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file to watch"), mcp.Required()),
+			mcp.WithString("source", mcp.Description("Optional diagnostic source to filter by, e.g. gopls or eslint")),
+			mcp.WithString("severity", mcp.Description("Optional severity to filter by"), mcp.Enum(diagnosticSeverities...)),
+			mcp.WithNumber("duration", mcp.Description("How long to watch in seconds (default 30)"), mcp.Min(1), mcp.Max(300)),
+			mcp.WithBoolean("untilClean", mcp.Description("Stop as soon as no diagnostics match the filters")),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register getSelection tool
+	addTool(
+		mcp.NewTool("getSelection",
+			mcp.WithDescription(`Get the text the user has selected in the active editor, one entry per cursor.
+
+Use this for "explain this" or "refactor this" requests that refer to highlighted code.
+
+Example:
+- Get selection: {}
+
+Returns:
+- [{"path": "/path/to/file.ts", "text": "const user = await load(id);",
+  "range": {"startLine": 10, "startCharacter": 4, "endLine": 10, "endCharacter": 32}, "empty": false}, ...]
+- With multiple cursors there is one entry per selection, the primary selection first
+- [] if no editor is focused
+
+Notes:
+- Lines are 1-based, characters are 0-based
+- A cursor without selection has empty: true, text "" and a range where start equals end
+- Text includes unsaved editor changes`+windowIdNote),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register setSelection tool
+	addTool(
+		mcp.NewTool("setSelection",
+			mcp.WithDescription(`Set the selection(s) or cursor in a file to direct the user's attention to code.
+
+The file is opened if needed. Use this to walk the user through code step by step.
+
+Examples:
+- Select a range: {"path": "/path/to/file.ts", "selections": [{"start": {"line": 10, "character": 0},
+  "end": {"line": 14, "character": 1}}], "reveal": true}
+- Place the cursor: {"path": "/path/to/file.ts", "selections": [{"start": {"line": 42, "character": 8}, "end": {"line": 42, "character": 8}}]}
+- Multiple cursors scrolled to top: {"path": "/path/to/file.ts", "selections": [
+  {"start": {"line": 3, "character": 6}, "end": {"line": 3, "character": 10}},
+  {"start": {"line": 7, "character": 6}, "end": {"line": 7, "character": 10}}], "reveal": "top"}
+
+Returns:
+- {"path": "/path/to/file.ts", "selections": [{"startLine": 10, "startCharacter": 0,
+  "endLine": 14, "endCharacter": 1}], "clamped": false}
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based, characters are 0-based
+- start is where the selection is anchored and end is where the cursor goes, so end may be before start
+- The first selection is the primary one
+- Positions past the end of a line or the document are clamped to the nearest valid position and
+  reported with clamped: true; the returned selections are the ones actually set
+- reveal scrolls the primary selection into view: true or "center" centers it, "top" scrolls it to
+  the top, "centerIfOutsideViewport" only scrolls if it isn't visible; default false`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			mcp.WithArray("selections", mcp.Description("Selections to set, each {start: {line, character}, end: {line, character}}"), mcp.Required(), mcp.Items(selectionSchema)),
+			withAny("reveal", mcp.Description("Scroll the primary selection into view: true, false, or a reveal mode"), withEnum(true, false, "center", "top", "centerIfOutsideViewport")),
+			withOffsetEncoding(),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register debugStart tool
+	addTool(
+		mcp.NewTool("debugStart",
+			mcp.WithDescription(`Start a debug session from a launch configuration.
+
+Examples:
+- Named configuration from launch.json: {"config": "Launch Server"}
+- In a specific workspace folder: {"config": "Launch Server", "folder": "/path/to/workspace"}
+- Inline configuration: {"config": {"type": "node", "request": "launch", "name": "Debug script",
+  "program": "/path/to/script.js"}}
+- Without debugging: {"config": "Launch Server", "noDebug": true}
+
+Returns:
+- {"started": true, "name": "Launch Server", "type": "node", "sessionId": "..."}
+- {"started": false, ...} if VS Code declined to start the session, e.g. because a pre-launch task failed
+
+Notes:
+- A named config is looked up in the launch.json of folder, or of every workspace folder and the
+  workspace file if folder is omitted; if it doesn't exist, the error lists the available names
+- Compound configurations can be started by name
+- An inline config needs at least type, request ("launch" or "attach"), and name
+- folder is optional and must be an absolute path of a workspace folder; it also resolves
+  variables like ${workspaceFolder} in the configuration
+- The tool returns once the session started; use the session's own output to follow it`+windowIdNote),
+			withAny("config", mcp.Description("Name of a launch configuration, or an inline configuration object"), mcp.Required(),
+				withOneOf(map[string]any{"type": "string"}, map[string]any{"type": "object"})),
+			mcp.WithString("folder", mcp.Description("Optional absolute path of the workspace folder to use")),
+			mcp.WithBoolean("noDebug", mcp.Description("Run the configuration without debugging (default false)")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register setBreakpoint tool
+	addTool(
+		mcp.NewTool("setBreakpoint",
+			mcp.WithDescription(`Add a source breakpoint on a line, optionally conditional or as a logpoint.
+
+Use this to prepare a debugging session before debugStart.
+
+Examples:
+- Breakpoint: {"path": "/path/to/server.ts", "line": 42}
+- Conditional: {"path": "/path/to/server.ts", "line": 42, "condition": "x > 5"}
+- Hit count: {"path": "/path/to/server.ts", "line": 42, "hitCondition": "10"}
+- Logpoint: {"path": "/path/to/server.ts", "line": 42, "logMessage": "user is {user.id}"}
+
+Returns:
+- {"path": "/path/to/server.ts", "line": 42, "condition": "x > 5", "enabled": true}
+
+Notes:
+- All paths must be absolute
+- line is 1-based
+- An existing breakpoint on the same line is replaced, so calling this again updates its condition
+- condition and hitCondition are expressions in the debugged language, evaluated by the debugger
+- logMessage turns the breakpoint into a logpoint that logs instead of stopping; {expr} is interpolated`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("Line to break on (1-based)"), mcp.Required(), mcp.Min(1)),
+			mcp.WithString("condition", mcp.Description("Optional expression that must be true to stop")),
+			mcp.WithString("hitCondition", mcp.Description("Optional hit count expression, e.g. \"10\" or \">= 3\"")),
+			mcp.WithString("logMessage", mcp.Description("Optional message to log instead of stopping")),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register clearBreakpoint tool
+	addTool(
+		mcp.NewTool("clearBreakpoint",
+			mcp.WithDescription(`Remove source breakpoints from a line or a whole file.
+
+Examples:
+- One line: {"path": "/path/to/server.ts", "line": 42}
+- Whole file: {"path": "/path/to/server.ts"}
+
+Returns:
+- {"removed": 1}
+
+Notes:
+- All paths must be absolute
+- line is 1-based; without it every breakpoint in the file is removed
+- Removing a breakpoint that doesn't exist is not an error, removed is then 0`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Absolute path of the file"), mcp.Required()),
+			withInteger("line", mcp.Description("Optional line of the breakpoint (1-based)"), mcp.Min(1)),
+			withWindowId(),
+		),
+		handleTool,
+	)
+
+	// Register listBreakpoints tool
+	addTool(
+		mcp.NewTool("listBreakpoints",
+			mcp.WithDescription(`List all breakpoints with their conditions and enabled state.
+
+Examples:
+- All breakpoints: {}
+- In one file: {"path": "/path/to/server.ts"}
+
+Returns:
+- [{"path": "/path/to/server.ts", "line": 42, "enabled": true, "condition": "x > 5"}, ...]
+- Function breakpoints have {"function": "handleRequest", "enabled": true} instead of path and line
+- [] if there are no breakpoints
+
+Notes:
+- All paths must be absolute
+- Lines are 1-based
+- hitCondition and logMessage are included when set`+windowIdNote),
+			mcp.WithString("path", mcp.Description("Optional absolute path to only list breakpoints in that file")),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register getWorkspaceFolders tool
+	addTool(
+		mcp.NewTool("getWorkspaceFolders",
+			mcp.WithDescription(`List the folders of the window's workspace.
+
+Use this to turn relative paths into the absolute paths other tools require.
+
+Example:
+- List folders: {}
+
+Returns:
+- [{"index": 0, "name": "api", "path": "/path/to/project/api", "uri": "file:///path/to/project/api"}, ...]
+- [] for a window without a folder open
+
+Notes:
+- Folders are in workspace order; index 0 is the first folder, which tools use as their default
+- path is only set for folders on the local file system, e.g. not for remote or virtual workspaces`+windowIdNote),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+
+	// Register getGitStatus tool
+	addTool(
+		mcp.NewTool("getGitStatus",
+			mcp.WithDescription(`Summarize the working tree state of a repository: staged, unstaged, and untracked files.
+
+Use this to decide what to review before opening gitDiff items for individual files.
+
+Examples:
+- Workspace repository: {}
+- Explicit repository: {"repo": "/path/to/repo"}
+
+Returns:
+- {"repo": "/path/to/repo", "branch": "main",
+  "staged": [{"status": "M", "path": "/path/to/repo/src/user.ts"}, ...],
+  "unstaged": [{"status": "D", "path": "/path/to/repo/src/old.ts"}, ...],
+  "untracked": [{"status": "?", "path": "/path/to/repo/src/new.ts"}, ...]}
+- Renamed files also carry the old path: {"status": "R", "path": "...", "from": "..."}
+
+Notes:
+- All paths are absolute
+- status is a git status code: M (modified), A (added), D (deleted), R (renamed), C (copied),
+  T (type changed), U (unmerged), or ? (untracked)
+- A file with both staged and unstaged changes is listed in both
+- repo defaults to the repository of the first workspace folder; fails with "not a git repository"
+  if there is none`+windowIdNote),
+			mcp.WithString("repo", mcp.Description("Optional absolute path of the git repository")),
+			withWindowId(),
+			mcp.WithReadOnlyHintAnnotation(true),
+		),
+		handleTool,
+	)
+}
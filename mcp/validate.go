@@ -0,0 +1,815 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// validateToolArgs checks tool arguments locally before they are sent to the
+// extension, so obviously bad requests fail fast with a clear message. It runs
+// after validateSchema and only checks what the schema can't express, like
+// absolute paths, non-empty strings, and arguments that depend on each other.
+func validateToolArgs(toolName string, args map[string]any) error {
+	switch toolName {
+	case "open":
+		if files, ok := args["files"]; ok {
+			return validateOpenItems(files)
+		}
+	case "getConfig":
+		if _, err := requireString(args, "key"); err != nil {
+			return err
+		}
+		if err := optionalAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+	case "backupDiff":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+	case "getBreadcrumbs":
+		if err := validateColumnPosition(args); err != nil {
+			return err
+		}
+	case "openDefinitionBeside":
+		if err := validateColumnPosition(args); err != nil {
+			return err
+		}
+	case "openScm":
+		if err := optionalAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+	case "fileHistoryDiff":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if err := optionalAbsolutePath(args, "repo"); err != nil {
+			return err
+		}
+		_, hasFrom := args["from"]
+		_, hasTo := args["to"]
+		if hasFrom != hasTo {
+			return fmt.Errorf("'from' and 'to' must be given together")
+		}
+		if hasFrom {
+			if _, err := requireString(args, "from"); err != nil {
+				return err
+			}
+			if _, err := requireString(args, "to"); err != nil {
+				return err
+			}
+		}
+	case "runScript":
+		if err := optionalString(args, "name"); err != nil {
+			return err
+		}
+		if err := optionalAbsolutePath(args, "folder"); err != nil {
+			return err
+		}
+	case "getDiagnostics":
+		if err := optionalAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if err := optionalString(args, "source"); err != nil {
+			return err
+		}
+	case "watchDiagnostics":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if err := optionalString(args, "source"); err != nil {
+			return err
+		}
+	case "showCommands":
+		if err := optionalString(args, "query"); err != nil {
+			return err
+		}
+	case "listCommands":
+		if err := optionalString(args, "filter"); err != nil {
+			return err
+		}
+	case "search":
+		if _, err := requireString(args, "query"); err != nil {
+			return err
+		}
+		if err := optionalString(args, "includeGlob"); err != nil {
+			return err
+		}
+	case "resolveImport":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if _, err := requireString(args, "importSpec"); err != nil {
+			return err
+		}
+	case "getFileContent":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if err := validateLineRange(args); err != nil {
+			return err
+		}
+	case "goToDefinition":
+		if err := validatePosition(args); err != nil {
+			return err
+		}
+		open, _ := args["open"].(bool)
+		peek, _ := args["peek"].(bool)
+		if open && peek {
+			return fmt.Errorf("pass either 'open' or 'peek', not both")
+		}
+	case "findReferences":
+		if err := validatePosition(args); err != nil {
+			return err
+		}
+	case "rename":
+		if err := validatePosition(args); err != nil {
+			return err
+		}
+		if _, err := requireString(args, "newName"); err != nil {
+			return err
+		}
+	case "rulers":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		clear, _ := args["clear"].(bool)
+		columns, hasColumns := args["columns"].([]any)
+		if clear == hasColumns {
+			return fmt.Errorf("exactly one of 'columns' or 'clear: true' must be given")
+		}
+		if hasColumns && len(columns) == 0 {
+			return fmt.Errorf("parameter 'columns' must be a non-empty array of column numbers")
+		}
+	case "organizeImports":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+	case "gitStashList", "getGitStatus":
+		if err := optionalAbsolutePath(args, "repo"); err != nil {
+			return err
+		}
+	case "gitStash":
+		if err := optionalAbsolutePath(args, "repo"); err != nil {
+			return err
+		}
+		action, _ := args["action"].(string)
+		if action == "push" {
+			if err := optionalString(args, "message"); err != nil {
+				return err
+			}
+		} else if err := optionalString(args, "stash"); err != nil {
+			return err
+		}
+		if confirm, _ := args["confirm"].(bool); action == "drop" && !confirm {
+			return fmt.Errorf("dropping a stash discards it permanently, pass 'confirm: true' to proceed")
+		}
+	case "showMessage":
+		if _, err := requireString(args, "message"); err != nil {
+			return err
+		}
+		if err := optionalStringArray(args, "actions"); err != nil {
+			return err
+		}
+	case "terminal":
+		if _, err := requireString(args, "command"); err != nil {
+			return err
+		}
+		if err := optionalAbsolutePath(args, "cwd"); err != nil {
+			return err
+		}
+		if err := optionalString(args, "name"); err != nil {
+			return err
+		}
+	case "listTodos":
+		if err := optionalString(args, "include"); err != nil {
+			return err
+		}
+		if err := optionalStringArray(args, "tags"); err != nil {
+			return err
+		}
+	case "gitLog":
+		if err := optionalAbsolutePath(args, "repo"); err != nil {
+			return err
+		}
+		if err := optionalAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+	case "openCommit":
+		if _, err := requireString(args, "commit"); err != nil {
+			return err
+		}
+		if err := optionalAbsolutePath(args, "repo"); err != nil {
+			return err
+		}
+	case "gitRestore":
+		path, err := requireAbsolutePath(args, "path")
+		if err != nil {
+			return err
+		}
+		if err := optionalString(args, "ref"); err != nil {
+			return err
+		}
+		if _, ok := args["repo"]; ok {
+			repo, err := requireAbsolutePath(args, "repo")
+			if err != nil {
+				return err
+			}
+			if !isWithin(repo, path) {
+				return fmt.Errorf("path '%s' is not inside repository '%s'", path, repo)
+			}
+		}
+		if confirm, _ := args["confirm"].(bool); !confirm {
+			return fmt.Errorf("restoring discards local changes to the file, pass 'confirm: true' to proceed")
+		}
+	case "gitBlame":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if err := validateLineRange(args); err != nil {
+			return err
+		}
+	case "saveAndClose":
+		if err := optionalAbsolutePathArray(args, "paths"); err != nil {
+			return err
+		}
+		all, _ := args["all"].(bool)
+		_, hasPaths := args["paths"]
+		if all == hasPaths {
+			return fmt.Errorf("pass either 'paths' or 'all: true'")
+		}
+	case "applyEdit":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if err := validateTextEdits(args["edits"]); err != nil {
+			return err
+		}
+	case "formatDocument":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if err := validateLineRange(args); err != nil {
+			return err
+		}
+		if _, hasEnd := args["endLine"]; hasEnd {
+			if _, hasStart := args["startLine"]; !hasStart {
+				return fmt.Errorf("endLine requires startLine")
+			}
+		}
+	case "codeActions":
+		if err := validatePosition(args); err != nil {
+			return err
+		}
+		if err := optionalString(args, "apply"); err != nil {
+			return err
+		}
+	case "getHover":
+		if err := validatePosition(args); err != nil {
+			return err
+		}
+	case "moveFile":
+		from, err := requireAbsolutePath(args, "from")
+		if err != nil {
+			return err
+		}
+		to, err := requireAbsolutePath(args, "to")
+		if err != nil {
+			return err
+		}
+		if filepath.Clean(from) == filepath.Clean(to) {
+			return fmt.Errorf("from and to are the same path '%s'", from)
+		}
+	case "setSelection":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		if selections, ok := args["selections"].([]any); !ok || len(selections) == 0 {
+			return fmt.Errorf("parameter 'selections' must be a non-empty array of {start, end} objects")
+		}
+	case "debugStart":
+		switch config := args["config"].(type) {
+		case string:
+			if config == "" {
+				return fmt.Errorf("parameter 'config' must not be empty")
+			}
+		case map[string]any:
+			for _, name := range []string{"type", "request", "name"} {
+				if value, ok := config[name].(string); !ok || value == "" {
+					return fmt.Errorf("inline config must have a non-empty string '%s'", name)
+				}
+			}
+			if request := config["request"]; request != "launch" && request != "attach" {
+				return fmt.Errorf("invalid config.request '%v', must be one of: launch, attach", request)
+			}
+		default:
+			return fmt.Errorf("missing 'config' parameter, must be a configuration name or object")
+		}
+		if err := optionalAbsolutePath(args, "folder"); err != nil {
+			return err
+		}
+	case "setBreakpoint":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+		for _, name := range []string{"condition", "hitCondition", "logMessage"} {
+			if err := optionalString(args, name); err != nil {
+				return err
+			}
+		}
+	case "clearBreakpoint":
+		if _, err := requireAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+	case "listBreakpoints":
+		if err := optionalAbsolutePath(args, "path"); err != nil {
+			return err
+		}
+	case "setDefaultWindow":
+		if clear, _ := args["clear"].(bool); clear {
+			if _, ok := args["windowId"]; ok {
+				return fmt.Errorf("pass either 'windowId' or 'clear', not both")
+			}
+			return nil
+		}
+		if _, err := requireString(args, "windowId"); err != nil {
+			return err
+		}
+	case "setConfig":
+		if _, err := requireString(args, "key"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diagnosticSeverities are the severities accepted by diagnostics filters
+var diagnosticSeverities = []string{"error", "warning", "information", "hint"}
+
+// revealModes are the ways a range can be scrolled into view
+var revealModes = []string{"center", "top", "centerIfOutsideViewport"}
+
+// eolModes are the line ending choices for written content. auto keeps the
+// document's existing line endings, or uses files.eol for new files.
+var eolModes = []string{"lf", "crlf", "auto"}
+
+// offsetEncodings are the units a character offset can be given in. VS Code
+// positions count UTF-16 code units.
+var offsetEncodings = []string{"utf16", "utf8", "codepoint"}
+
+// fileEncodings are the encoding identifiers VS Code accepts for files.
+var fileEncodings = []string{
+	"big5hkscs", "cp437", "cp850", "cp852", "cp865", "cp866", "cp950", "cp1125",
+	"eucjp", "euckr", "gb2312", "gb18030", "gbk",
+	"iso88591", "iso88592", "iso88593", "iso88594", "iso88595", "iso88596", "iso88597",
+	"iso88598", "iso88599", "iso885910", "iso885911", "iso885913", "iso885914",
+	"iso885915", "iso885916", "koi8r", "koi8ru", "koi8t", "koi8u", "macroman", "shiftjis",
+	"utf8", "utf8bom", "utf16be", "utf16le",
+	"windows874", "windows1250", "windows1251", "windows1252", "windows1253",
+	"windows1254", "windows1255", "windows1256", "windows1257", "windows1258",
+}
+
+// openItemValidators validates each item type accepted by the open tool.
+var openItemValidators = map[string]func(item map[string]any) error{
+	"file": func(item map[string]any) error {
+		// uri wins over path, so path is only checked without it
+		if _, hasURI := item["uri"]; hasURI {
+			if err := requireURI(item, "uri"); err != nil {
+				return err
+			}
+		} else if _, err := requireAbsolutePath(item, "path"); err != nil {
+			return fmt.Errorf("%v (or pass a 'uri')", err)
+		}
+		if err := validateLineRange(item); err != nil {
+			return err
+		}
+		if _, hasEnd := item["endLine"]; hasEnd {
+			if _, hasStart := item["startLine"]; !hasStart {
+				return fmt.Errorf("endLine requires startLine")
+			}
+		}
+		// The schema only declares a string, the list of encodings is long
+		return optionalEnum(item, "encoding", fileEncodings...)
+	},
+	"diff": func(item map[string]any) error {
+		if _, err := requireAbsolutePath(item, "left"); err != nil {
+			return err
+		}
+		if _, ok := item["rightContent"]; ok {
+			if _, ok := item["right"]; ok {
+				return fmt.Errorf("pass either 'right' or 'rightContent', not both")
+			}
+			return nil
+		}
+		_, err := requireAbsolutePath(item, "right")
+		return err
+	},
+	"gitDiff": func(item map[string]any) error {
+		changedOnly, _ := item["changedOnly"].(bool)
+		if active, _ := item["active"].(bool); active {
+			// The active editor's file is diffed, resolved by the extension
+			if _, ok := item["path"]; ok {
+				return fmt.Errorf("pass either 'path' or 'active', not both")
+			}
+			if changedOnly {
+				return fmt.Errorf("pass either 'active' or 'changedOnly', not both")
+			}
+		} else if changedOnly {
+			// Multi-file mode diffs every changed file, path optionally selects the repository
+			if err := optionalAbsolutePath(item, "path"); err != nil {
+				return err
+			}
+		} else if _, err := requireAbsolutePath(item, "path"); err != nil {
+			return err
+		}
+		from, err := requireString(item, "from")
+		if err != nil {
+			return err
+		}
+		to, err := requireString(item, "to")
+		if err != nil {
+			return err
+		}
+		if from == to {
+			return fmt.Errorf("from and to are both '%s', nothing to compare", from)
+		}
+		return nil
+	},
+	"insert": func(item map[string]any) error {
+		if _, err := requireAbsolutePath(item, "path"); err != nil {
+			return err
+		}
+		// Open items only require their type in the schema
+		for _, name := range []string{"line", "character", "text"} {
+			if _, ok := item[name]; !ok {
+				return fmt.Errorf("missing '%s' parameter", name)
+			}
+		}
+		return nil
+	},
+	"notebook": func(item map[string]any) error {
+		path, err := requireAbsolutePath(item, "path")
+		if err != nil {
+			return err
+		}
+		if _, ok := item["notebookType"]; ok {
+			_, err := requireString(item, "notebookType")
+			return err
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".ipynb") {
+			return fmt.Errorf("'%s' is not a .ipynb file, pass notebookType to open it with another notebook provider", path)
+		}
+		return nil
+	},
+	"reveal": func(item map[string]any) error {
+		_, err := requireAbsolutePath(item, "path")
+		return err
+	},
+	"openFolder": func(item map[string]any) error {
+		_, err := requireAbsolutePath(item, "path")
+		return err
+	},
+	"createFile": func(item map[string]any) error {
+		if _, err := requireAbsolutePath(item, "path"); err != nil {
+			return err
+		}
+		if _, ok := item["content"]; !ok {
+			return fmt.Errorf("missing 'content' parameter")
+		}
+		return nil
+	},
+}
+
+// openItemTypes returns the valid open item types in sorted order.
+func openItemTypes() []string {
+	types := make([]string, 0, len(openItemValidators))
+	for itemType := range openItemValidators {
+		types = append(types, itemType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// validateOpenItems validates the open tool's files argument, which is either
+// a single item or an array of items. Errors for arrays include the index.
+func validateOpenItems(files any) error {
+	items, isArray := files.([]any)
+	if !isArray {
+		return validateOpenItem(files)
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("'files' must contain at least one item")
+	}
+	for i, item := range items {
+		if err := validateOpenItem(item); err != nil {
+			return fmt.Errorf("item %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// validateOpenItem checks a single open item's type discriminator and its
+// required fields.
+func validateOpenItem(value any) error {
+	item, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("each item must be an object with a 'type' field")
+	}
+	itemType, ok := item["type"].(string)
+	if !ok {
+		return fmt.Errorf("missing 'type' field, valid types: %s", strings.Join(openItemTypes(), ", "))
+	}
+	validate, ok := openItemValidators[itemType]
+	if !ok {
+		return fmt.Errorf("unknown type '%s', valid types: %s", itemType, strings.Join(openItemTypes(), ", "))
+	}
+	if err := validate(item); err != nil {
+		return fmt.Errorf("invalid %s item: %v", itemType, err)
+	}
+	return nil
+}
+
+// validatePosition validates the path shared by position-based tools, the
+// schema covers their 1-based line and 0-based character.
+func validatePosition(args map[string]any) error {
+	_, err := requireAbsolutePath(args, "path")
+	return err
+}
+
+// validateColumnPosition validates a position given as a 1-based line and
+// either a 1-based column or, like the other position tools, a 0-based
+// character. toolArgs turns the column into a character.
+func validateColumnPosition(args map[string]any) error {
+	if err := validatePosition(args); err != nil {
+		return err
+	}
+	_, hasColumn := args["column"]
+	_, hasCharacter := args["character"]
+	switch {
+	case hasColumn && hasCharacter:
+		return fmt.Errorf("pass either 'column' or 'character', not both")
+	case !hasColumn && !hasCharacter:
+		return fmt.Errorf("missing 'column' parameter")
+	}
+	return nil
+}
+
+// validateLineRange validates that the optional 1-based startLine/endLine
+// pair is in order.
+func validateLineRange(args map[string]any) error {
+	startLine, hasStart := args["startLine"].(float64)
+	endLine, hasEnd := args["endLine"].(float64)
+	if hasStart && hasEnd && endLine < startLine {
+		return fmt.Errorf("endLine (%d) must not be before startLine (%d)", int(endLine), int(startLine))
+	}
+	return nil
+}
+
+// textPosition is a 1-based line and 0-based character within a document.
+type textPosition struct {
+	line, character int
+}
+
+func (p textPosition) before(other textPosition) bool {
+	return p.line < other.line || (p.line == other.line && p.character < other.character)
+}
+
+func (p textPosition) String() string {
+	return fmt.Sprintf("%d:%d", p.line, p.character)
+}
+
+// requireRange returns the start and end of a range object with startLine,
+// startCharacter, endLine, and endCharacter, ensuring end is not before start.
+func requireRange(args map[string]any, name string) (textPosition, textPosition, error) {
+	fields, ok := args[name].(map[string]any)
+	if !ok {
+		return textPosition{}, textPosition{}, fmt.Errorf("parameter '%s' must be an object with startLine, startCharacter, endLine, and endCharacter", name)
+	}
+	var values [4]int
+	for i, field := range []string{"startLine", "startCharacter", "endLine", "endCharacter"} {
+		min := 0
+		if i%2 == 0 {
+			min = 1
+		}
+		value, err := requireInteger(fields, field, min)
+		if err != nil {
+			return textPosition{}, textPosition{}, fmt.Errorf("%s: %v", name, err)
+		}
+		values[i] = value
+	}
+	start, end := textPosition{values[0], values[1]}, textPosition{values[2], values[3]}
+	if end.before(start) {
+		return textPosition{}, textPosition{}, fmt.Errorf("%s: end %v must not be before start %v", name, end, start)
+	}
+	return start, end, nil
+}
+
+// validateTextEdits validates a non-empty array of {range, newText} edits and
+// rejects overlapping ranges, which a single workspace edit can't apply.
+func validateTextEdits(value any) error {
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("parameter 'edits' must be a non-empty array of {range, newText} objects")
+	}
+
+	type editRange struct {
+		index      int
+		start, end textPosition
+	}
+	ranges := make([]editRange, 0, len(list))
+	for i, item := range list {
+		edit, ok := item.(map[string]any)
+		if !ok {
+			return fmt.Errorf("edit %d: must be an object", i)
+		}
+		start, end, err := requireRange(edit, "range")
+		if err != nil {
+			return fmt.Errorf("edit %d: %v", i, err)
+		}
+		if _, ok := edit["newText"].(string); !ok {
+			return fmt.Errorf("edit %d: parameter 'newText' must be a string", i)
+		}
+		ranges = append(ranges, editRange{i, start, end})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].start != ranges[j].start {
+			return ranges[i].start.before(ranges[j].start)
+		}
+		return ranges[i].end.before(ranges[j].end)
+	})
+	for i := 1; i < len(ranges); i++ {
+		prev, next := ranges[i-1], ranges[i]
+		if next.start.before(prev.end) {
+			return fmt.Errorf("edit %d (%v-%v) overlaps edit %d (%v-%v)", next.index, next.start, next.end, prev.index, prev.start, prev.end)
+		}
+	}
+	return nil
+}
+
+// requireString returns the non-empty string argument with the given name.
+func requireString(args map[string]any, name string) (string, error) {
+	value, ok := args[name]
+	if !ok {
+		return "", fmt.Errorf("missing '%s' parameter", name)
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("parameter '%s' must be a string", name)
+	}
+	if strings.TrimSpace(str) == "" {
+		return "", fmt.Errorf("parameter '%s' must not be empty", name)
+	}
+	return str, nil
+}
+
+// optionalString validates the string argument with the given name if it is
+// present.
+func optionalString(args map[string]any, name string) error {
+	if _, ok := args[name]; !ok {
+		return nil
+	}
+	_, err := requireString(args, name)
+	return err
+}
+
+// requireAbsolutePath returns the string argument with the given name,
+// ensuring it is an absolute path.
+func requireAbsolutePath(args map[string]any, name string) (string, error) {
+	path, err := requireString(args, name)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("parameter '%s' must be an absolute path, got '%s'", name, path)
+	}
+	return path, nil
+}
+
+// requireURI validates the string argument with the given name as a URI
+// with a scheme, like file:///tmp/a.txt or untitled:Untitled-1. Single
+// letter schemes are rejected, as those are Windows drive letters.
+func requireURI(args map[string]any, name string) error {
+	value, err := requireString(args, name)
+	if err != nil {
+		return err
+	}
+	uri, err := url.Parse(value)
+	if err != nil || len(uri.Scheme) < 2 {
+		return fmt.Errorf("parameter '%s' must be a URI with a scheme, e.g. untitled:Untitled-1 or vscode-remote://ssh-remote+host/path, got '%s'", name, value)
+	}
+	return nil
+}
+
+// isWithin reports whether path is dir itself or located below it.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// optionalAbsolutePath validates the path argument with the given name if it
+// is present.
+func optionalAbsolutePath(args map[string]any, name string) error {
+	if _, ok := args[name]; !ok {
+		return nil
+	}
+	_, err := requireAbsolutePath(args, name)
+	return err
+}
+
+// requireBool returns the boolean argument with the given name.
+func requireBool(args map[string]any, name string) (bool, error) {
+	value, ok := args[name]
+	if !ok {
+		return false, fmt.Errorf("missing '%s' parameter", name)
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("parameter '%s' must be a boolean", name)
+	}
+	return b, nil
+}
+
+// optionalEnum validates that the string argument with the given name, if
+// present, is one of the allowed values.
+func optionalEnum(args map[string]any, name string, allowed ...string) error {
+	if _, ok := args[name]; !ok {
+		return nil
+	}
+	value, err := requireString(args, name)
+	if err != nil {
+		return err
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s '%s', must be one of: %s", name, value, strings.Join(allowed, ", "))
+}
+
+// optionalStringArray validates that the argument with the given name, if
+// present, is a non-empty array of non-empty strings.
+func optionalStringArray(args map[string]any, name string) error {
+	value, ok := args[name]
+	if !ok {
+		return nil
+	}
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return fmt.Errorf("parameter '%s' must be a non-empty array of strings", name)
+	}
+	for i, item := range list {
+		if str, ok := item.(string); !ok || strings.TrimSpace(str) == "" {
+			return fmt.Errorf("%s[%d] must be a non-empty string", name, i)
+		}
+	}
+	return nil
+}
+
+// optionalAbsolutePathArray validates that the argument with the given name,
+// if present, is a non-empty array of absolute paths.
+func optionalAbsolutePathArray(args map[string]any, name string) error {
+	if err := optionalStringArray(args, name); err != nil {
+		return err
+	}
+	list, _ := args[name].([]any)
+	for i, item := range list {
+		if path := item.(string); !filepath.IsAbs(path) {
+			return fmt.Errorf("%s[%d] must be an absolute path, got '%s'", name, i, path)
+		}
+	}
+	return nil
+}
+
+// optionalBool validates the boolean argument with the given name if it is
+// present.
+func optionalBool(args map[string]any, name string) error {
+	if _, ok := args[name]; !ok {
+		return nil
+	}
+	_, err := requireBool(args, name)
+	return err
+}
+
+// requireInteger returns the integer argument with the given name, ensuring
+// it is at least min.
+func requireInteger(args map[string]any, name string, min int) (int, error) {
+	value, ok := args[name]
+	if !ok {
+		return 0, fmt.Errorf("missing '%s' parameter", name)
+	}
+	n, ok := value.(float64)
+	if !ok || n != float64(int(n)) {
+		return 0, fmt.Errorf("parameter '%s' must be an integer", name)
+	}
+	if int(n) < min {
+		return 0, fmt.Errorf("parameter '%s' must be at least %d, got %d", name, min, int(n))
+	}
+	return int(n), nil
+}
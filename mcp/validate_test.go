@@ -0,0 +1,335 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registerSchemas fills toolSchemas once, as starting the server would.
+var registerSchemas = sync.OnceFunc(func() {
+	registerTools(server.NewMCPServer("vs-claude-test", serverVersion))
+})
+
+// edit returns a {range, newText} edit as it arrives in tool arguments.
+func edit(startLine, startCharacter, endLine, endCharacter int, newText string) any {
+	return map[string]any{
+		"range": map[string]any{
+			"startLine":      float64(startLine),
+			"startCharacter": float64(startCharacter),
+			"endLine":        float64(endLine),
+			"endCharacter":   float64(endCharacter),
+		},
+		"newText": newText,
+	}
+}
+
+func TestValidateTextEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		edits   any
+		wantErr string
+	}{
+		{
+			name:  "single edit",
+			edits: []any{edit(1, 0, 1, 5, "x")},
+		},
+		{
+			name:  "adjacent edits",
+			edits: []any{edit(1, 0, 1, 5, "x"), edit(1, 5, 2, 0, "y")},
+		},
+		{
+			name:  "inserts at the same position",
+			edits: []any{edit(3, 2, 3, 2, "a"), edit(3, 2, 3, 2, "b")},
+		},
+		{
+			name:    "overlap on one line",
+			edits:   []any{edit(1, 0, 1, 5, "x"), edit(1, 4, 1, 8, "y")},
+			wantErr: "edit 1 (1:4-1:8) overlaps edit 0 (1:0-1:5)",
+		},
+		{
+			name:    "overlap across lines, out of order",
+			edits:   []any{edit(10, 0, 12, 0, "x"), edit(2, 0, 2, 1, "y"), edit(5, 3, 11, 0, "z")},
+			wantErr: "edit 0 (10:0-12:0) overlaps edit 2 (5:3-11:0)",
+		},
+		{
+			name:    "insert inside a replaced range",
+			edits:   []any{edit(4, 0, 6, 0, "x"), edit(5, 0, 5, 0, "y")},
+			wantErr: "edit 1 (5:0-5:0) overlaps edit 0 (4:0-6:0)",
+		},
+		{
+			name:    "end before start",
+			edits:   []any{edit(2, 0, 1, 0, "x")},
+			wantErr: "edit 0: range: end 1:0 must not be before start 2:0",
+		},
+		{
+			name:    "missing newText",
+			edits:   []any{map[string]any{"range": edit(1, 0, 1, 0, "").(map[string]any)["range"]}},
+			wantErr: "edit 0: parameter 'newText' must be a string",
+		},
+		{
+			name:    "empty",
+			edits:   []any{},
+			wantErr: "must be a non-empty array",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTextEdits(tt.edits)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateTextEdits() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateTextEdits() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	registerSchemas()
+	position := func(extra map[string]any) map[string]any {
+		args := map[string]any{"path": "/tmp/a.go", "line": float64(3), "character": float64(1)}
+		for name, value := range extra {
+			args[name] = value
+		}
+		return args
+	}
+
+	tests := []struct {
+		name    string
+		tool    string
+		args    map[string]any
+		wantErr string
+	}{
+		{
+			name: "valid position with common parameters",
+			tool: "getHover",
+			args: position(map[string]any{"windowId": "window-1", "offsetEncoding": "utf8"}),
+		},
+		{
+			name:    "unknown field",
+			tool:    "getHover",
+			args:    position(map[string]any{"colum": float64(2)}),
+			wantErr: "unknown parameter 'colum'",
+		},
+		{
+			name:    "missing required field",
+			tool:    "getHover",
+			args:    map[string]any{"path": "/tmp/a.go", "line": float64(3)},
+			wantErr: "missing 'character' parameter",
+		},
+		{
+			name:    "wrong type",
+			tool:    "getHover",
+			args:    position(map[string]any{"line": "3"}),
+			wantErr: "parameter 'line' must be an integer, got string",
+		},
+		{
+			name:    "below minimum",
+			tool:    "getHover",
+			args:    position(map[string]any{"line": float64(0)}),
+			wantErr: "parameter 'line' must be at least 1",
+		},
+		{
+			name:    "fractional line",
+			tool:    "getHover",
+			args:    position(map[string]any{"line": 2.5}),
+			wantErr: "parameter 'line' must be an integer",
+		},
+		{
+			name:    "fractional maxResults",
+			tool:    "search",
+			args:    map[string]any{"query": "logger", "maxResults": 2.5},
+			wantErr: "parameter 'maxResults' must be an integer",
+		},
+		{
+			name:    "fractional count",
+			tool:    "diagnoseConnection",
+			args:    map[string]any{"count": 2.5},
+			wantErr: "parameter 'count' must be an integer",
+		},
+		{
+			name:    "fractional max",
+			tool:    "gitLog",
+			args:    map[string]any{"max": 2.5},
+			wantErr: "parameter 'max' must be an integer",
+		},
+		{
+			name:    "column below 1",
+			tool:    "getBreadcrumbs",
+			args:    map[string]any{"path": "/tmp/a.go", "line": float64(3), "column": float64(0)},
+			wantErr: "parameter 'column' must be at least 1",
+		},
+		{
+			name:    "invalid enum",
+			tool:    "getHover",
+			args:    position(map[string]any{"offsetEncoding": "utf32"}),
+			wantErr: "invalid offsetEncoding utf32, must be one of",
+		},
+		{
+			name: "field path into an array of open items",
+			tool: "open",
+			args: map[string]any{"files": []any{
+				map[string]any{"type": "file", "path": "/tmp/a.go"},
+				map[string]any{"type": "file", "path": "/tmp/b.go", "startLine": "ten"},
+			}},
+			wantErr: "files[1].startLine",
+		},
+		{
+			name: "unknown field in an open item",
+			tool: "open",
+			args: map[string]any{"files": []any{
+				map[string]any{
+					"type": "insert", "path": "/tmp/a.go", "line": float64(1), "character": float64(0),
+					"text": "x", "colum": float64(1),
+				},
+			}},
+			wantErr: "files[0].colum",
+		},
+		{
+			name:    "open item not a boolean",
+			tool:    "open",
+			args:    map[string]any{"files": map[string]any{"type": "file", "path": "/tmp/a.go", "newWindow": "yes"}},
+			wantErr: "parameter 'files.newWindow' must be a boolean",
+		},
+		{
+			name: "invalid open item enum",
+			tool: "open",
+			args: map[string]any{"files": map[string]any{
+				"type": "insert", "path": "/tmp/a.go", "line": float64(1), "character": float64(0),
+				"text": "x", "eol": "cr",
+			}},
+			wantErr: "eol cr, must be one of: lf, crlf, auto",
+		},
+		{
+			name:    "custom handler above maximum",
+			tool:    "diagnoseConnection",
+			args:    map[string]any{"count": float64(51)},
+			wantErr: "parameter 'count' must be at most 50, got 51",
+		},
+		{
+			name:    "custom handler without parameters",
+			tool:    "listWindows",
+			args:    map[string]any{"windowId": "window-1"},
+			wantErr: "unknown parameter 'windowId'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchema(tt.tool, tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateSchema() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateSchema() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateToolArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		args    map[string]any
+		wantErr string
+	}{
+		{
+			name: "valid position",
+			tool: "getHover",
+			args: map[string]any{"path": "/tmp/a.go", "line": float64(3), "character": float64(0)},
+		},
+		{
+			name: "position with a column",
+			tool: "getBreadcrumbs",
+			args: map[string]any{"path": "/tmp/a.go", "line": float64(3), "column": float64(1)},
+		},
+		{
+			name: "definition beside with a character",
+			tool: "openDefinitionBeside",
+			args: map[string]any{"path": "/tmp/a.go", "line": float64(3), "character": float64(0)},
+		},
+		{
+			name:    "definition beside without a position",
+			tool:    "openDefinitionBeside",
+			args:    map[string]any{"path": "/tmp/a.go", "line": float64(3)},
+			wantErr: "missing 'column' parameter",
+		},
+		{
+			name:    "both column and character",
+			tool:    "getBreadcrumbs",
+			args:    map[string]any{"path": "/tmp/a.go", "line": float64(3), "column": float64(1), "character": float64(0)},
+			wantErr: "pass either 'column' or 'character', not both",
+		},
+		{
+			name:    "neither column nor character",
+			tool:    "getBreadcrumbs",
+			args:    map[string]any{"path": "/tmp/a.go", "line": float64(3)},
+			wantErr: "missing 'column' parameter",
+		},
+		{
+			name:    "relative path",
+			tool:    "getHover",
+			args:    map[string]any{"path": "a.go", "line": float64(1), "character": float64(0)},
+			wantErr: "parameter 'path' must be an absolute path, got 'a.go'",
+		},
+		{
+			name: "unknown open item type",
+			tool: "open",
+			args: map[string]any{"files": []any{
+				map[string]any{"type": "file", "path": "/tmp/a.go"},
+				map[string]any{"type": "picture"},
+			}},
+			wantErr: "item 1: unknown type 'picture', valid types: ",
+		},
+		{
+			name:    "open item without type",
+			tool:    "open",
+			args:    map[string]any{"files": map[string]any{"path": "/tmp/a.go"}},
+			wantErr: "missing 'type' field",
+		},
+		{
+			name: "file in a new window",
+			tool: "open",
+			args: map[string]any{"files": map[string]any{"type": "file", "path": "/tmp/a.go", "newWindow": true}},
+		},
+		{
+			name:    "notebook without notebookType",
+			tool:    "open",
+			args:    map[string]any{"files": map[string]any{"type": "notebook", "path": "/tmp/book.md"}},
+			wantErr: "pass notebookType",
+		},
+		{
+			name:    "both open and peek",
+			tool:    "goToDefinition",
+			args:    map[string]any{"path": "/tmp/a.go", "line": float64(1), "character": float64(0), "open": true, "peek": true},
+			wantErr: "pass either 'open' or 'peek', not both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateToolArgs(tt.tool, tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateToolArgs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateToolArgs() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
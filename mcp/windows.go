@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type WindowInfo struct {
+	WindowID    string    `json:"windowId,omitempty"`
+	Workspace   string    `json:"workspace"`
+	WindowTitle string    `json:"windowTitle"`
+	Timestamp   time.Time `json:"timestamp"`
+	PID         int       `json:"pid,omitempty"`
+	// Folders are the local paths of the workspace folders, in workspace order
+	Folders []string `json:"folders,omitempty"`
+
+	// stale is set by scanWindows for windows whose heartbeat stopped
+	stale bool
+}
+
+// pidString returns the PID for display, or "unknown" for metadata written
+// by older extension versions that don't report it.
+func (w *WindowInfo) pidString() string {
+	if w.PID <= 0 {
+		return "unknown"
+	}
+	return strconv.Itoa(w.PID)
+}
+
+// windowListEntry is a single window as reported by the listWindows tool
+type windowListEntry struct {
+	WindowID    string    `json:"windowId"`
+	Workspace   string    `json:"workspace"`
+	WindowTitle string    `json:"windowTitle"`
+	Timestamp   time.Time `json:"timestamp"`
+	Stale       bool      `json:"stale"`
+	Default     bool      `json:"default,omitempty"`
+}
+
+// handleListWindows lists all known VS Code windows. Unlike other tools it is
+// answered locally and never needs a target window.
+func handleListWindows(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if err := validateSchema("listWindows", request.GetArguments()); err != nil {
+		return nil, err
+	}
+	windows, warning, err := scanWindows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active windows: %v", err)
+	}
+
+	defaultWindow := readDefaultWindow()
+	entries := make([]windowListEntry, 0, len(windows))
+	for id, info := range windows {
+		entries = append(entries, windowListEntry{
+			WindowID:    id,
+			Workspace:   info.Workspace,
+			WindowTitle: info.WindowTitle,
+			Timestamp:   info.Timestamp,
+			Stale:       info.stale,
+			Default:     id == defaultWindow && !info.stale,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].WindowID < entries[j].WindowID
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal windows: %v", err)
+	}
+
+	result := mcp.NewToolResultText(string(data))
+	if warning != "" {
+		result.Content = append(result.Content, mcp.NewTextContent("Warning: "+warning))
+	}
+	return result, nil
+}
+
+func getTargetWindow(ctx context.Context, windowId *string) (string, error) {
+	id, _, err := getTargetWindowInfo(ctx, windowId)
+	return id, err
+}
+
+// getTargetWindowInfo is like getTargetWindow, but also returns the
+// window's metadata.
+func getTargetWindowInfo(ctx context.Context, windowId *string) (string, *WindowInfo, error) {
+	windows, warning, err := getActiveWindows(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get active windows: %v", err)
+	}
+	// With several windows to choose from, prefer the default window if set
+	// and still active
+	if (windowId == nil || *windowId == "") && len(windows) > 1 {
+		if defaultWindow := readDefaultWindow(); defaultWindow != "" {
+			if _, exists := windows[defaultWindow]; exists {
+				return defaultWindow, windows[defaultWindow], nil
+			}
+			logDebugf("Ignoring default window %s, it is no longer active", defaultWindow)
+		}
+	}
+	id, err := selectWindow(windows, windowId)
+	if err != nil {
+		if warning != "" {
+			return "", nil, fmt.Errorf("%v\n\nWarning: %s", err, warning)
+		}
+		return "", nil, err
+	}
+	return id, windows[id], nil
+}
+
+// selectWindow picks the window to send a command to: the requested one, or
+// the only one if none was requested.
+func selectWindow(windows map[string]*WindowInfo, windowId *string) (string, error) {
+
+	// If windowId specified, use it
+	if windowId != nil && *windowId != "" {
+		if _, exists := windows[*windowId]; exists {
+			return *windowId, nil
+		}
+		return "", fmt.Errorf("window with ID '%s' not found. Active windows: %d", *windowId, len(windows))
+	}
+
+	// If only one window, use it
+	if len(windows) == 1 {
+		for id := range windows {
+			return id, nil
+		}
+	}
+
+	// Multiple windows, need to specify
+	if len(windows) > 1 {
+		ids := make([]string, 0, len(windows))
+		for id := range windows {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		var windowList []string
+		for _, id := range ids {
+			info := windows[id]
+			windowList = append(windowList, fmt.Sprintf("- %s: %s (title: %s, pid: %s)", id, info.Workspace, info.WindowTitle, info.pidString()))
+		}
+		return "", fmt.Errorf("multiple VS Code windows found. Please specify a windowId:\n%s\n\nCall the tool again with the windowId parameter, or call setDefaultWindow to use one of them whenever windowId is omitted", strings.Join(windowList, "\n"))
+	}
+
+	return "", fmt.Errorf("no VS Code windows found")
+}
+
+// defaultWindowFile returns the path of the file recording the default
+// window, which is used when several windows are open and a tool call
+// doesn't name one.
+func defaultWindowFile() string {
+	return filepath.Join(vsClaudeDir, "default")
+}
+
+// readDefaultWindow returns the ID of the default window, or "" if none is
+// set. The window may no longer be active.
+func readDefaultWindow() string {
+	data, err := ipcFS.ReadFile(defaultWindowFile())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// handleSetDefaultWindow records the window to use when several windows are
+// open and a tool call doesn't pass a windowId, or clears it. Like
+// listWindows it is answered locally.
+func handleSetDefaultWindow(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+	if err := validateSchema("setDefaultWindow", args); err != nil {
+		return nil, err
+	}
+	if err := validateToolArgs("setDefaultWindow", args); err != nil {
+		return nil, err
+	}
+
+	if request.GetBool("clear", false) {
+		if err := ipcFS.Remove(defaultWindowFile()); err != nil && !os.IsNotExist(err) {
+			return nil, withPermissionHint(fmt.Errorf("failed to clear default window: %w", err))
+		}
+		return mcp.NewToolResultText(`{"defaultWindow":null}`), nil
+	}
+
+	windowId := request.GetString("windowId", "")
+	windows, _, err := getActiveWindows(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active windows: %v", err)
+	}
+	if _, exists := windows[windowId]; !exists {
+		return nil, fmt.Errorf("window with ID '%s' not found. Active windows: %d", windowId, len(windows))
+	}
+
+	f, err := ipcFS.OpenFile(defaultWindowFile(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, withPermissionHint(fmt.Errorf("failed to set default window: %w", err))
+	}
+	_, err = fmt.Fprintln(f, windowId)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set default window: %v", err)
+	}
+
+	data, err := json.Marshal(map[string]string{"defaultWindow": windowId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+func getActiveWindows(ctx context.Context) (map[string]*WindowInfo, string, error) {
+	windows, warning, err := scanWindows(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for id, info := range windows {
+		if info.stale {
+			delete(windows, id)
+		}
+	}
+
+	return windows, warning, nil
+}
+
+// windowScanTimeout bounds how long scanWindows may take, so a hung file
+// system under ~/.vs-claude can't block tool calls forever.
+const windowScanTimeout = 2 * time.Second
+
+// scanWindows reads all window metadata files. Windows whose metadata hasn't
+// been touched within the stale threshold are returned marked as stale, and
+// their files are cleaned up once they have been silent for much longer. If
+// the scan exceeds windowScanTimeout or ctx is done, the windows found so far
+// are returned together with a warning.
+func scanWindows(ctx context.Context) (map[string]*WindowInfo, string, error) {
+	windows := make(map[string]*WindowInfo)
+
+	// File system calls can't be interrupted, so scan in the background and
+	// stop waiting for it once the budget is used up
+	found := make(chan *WindowInfo)
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		errs <- readWindowFiles(func(info *WindowInfo) bool {
+			select {
+			case found <- info:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+
+	timeout := time.NewTimer(windowScanTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case info := <-found:
+			windows[info.WindowID] = info
+		case err := <-errs:
+			if err != nil {
+				return nil, "", err
+			}
+			return windows, "", nil
+		case <-timeout.C:
+			warning := fmt.Sprintf("window scan of %s timed out after %v, found %d window(s) so far", vsClaudeDir, windowScanTimeout, len(windows))
+			logWarnf("%s", warning)
+			return windows, warning, nil
+		case <-ctx.Done():
+			return windows, fmt.Sprintf("window scan interrupted: %v", ctx.Err()), nil
+		}
+	}
+}
+
+// readWindowFiles reads each window's metadata, passing it to emit until
+// emit returns false.
+func readWindowFiles(emit func(*WindowInfo) bool) error {
+	files, err := ipcFS.ReadDir(vsClaudeDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	now := time.Now()
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".meta.json") {
+			windowId := strings.TrimSuffix(file.Name(), ".meta.json")
+			filePath := filepath.Join(vsClaudeDir, file.Name())
+
+			// Check file modification time
+			fileInfo, err := ipcFS.Stat(filePath)
+			if err != nil {
+				continue
+			}
+
+			// If file hasn't been touched within the threshold, it's stale
+			sinceHeartbeat := now.Sub(fileInfo.ModTime())
+			stale := sinceHeartbeat > staleThreshold
+
+			// Read window metadata before a stale window's files are removed
+			info, readErr := readWindowMeta(filePath)
+
+			// Only clean up once the window has been silent for well past the
+			// threshold, and never while one of our commands is in flight
+			if stale && sinceHeartbeat > staleCleanupFactor*staleThreshold && !hasPendingCommands(windowId) {
+				// Clean up stale window files
+				ipcFS.Remove(filePath)
+				cmdFile := filepath.Join(vsClaudeDir, windowId+".in")
+				ipcFS.Remove(cmdFile)
+				ipcFS.Remove(cmdFile + ".lock")
+				respFile := filepath.Join(vsClaudeDir, windowId+".out")
+				ipcFS.Remove(respFile)
+				logInfof("Cleaned up stale window: %s", windowId)
+			}
+
+			if readErr != nil {
+				logWarnf("Skipping window %s: %v", windowId, readErr)
+				continue
+			}
+
+			// The file name is authoritative, the echoed ID is informational
+			info.WindowID = windowId
+			info.stale = stale
+			if !emit(info) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// metaReadRetries is how often an empty or cut-off metadata file is read
+// again, metaReadRetryDelay how long to wait before each attempt. The
+// extension rewrites the file on every heartbeat, so it can be caught
+// mid-write.
+const (
+	metaReadRetries    = 3
+	metaReadRetryDelay = 5 * time.Millisecond
+)
+
+// readWindowMeta reads and parses a window's metadata file. A file that is
+// empty, JSON null, or ends mid-value is retried, as the extension may be
+// rewriting it; anything else that fails to parse is malformed and returned
+// as an error right away.
+func readWindowMeta(filePath string) (*WindowInfo, error) {
+	for attempt := 0; ; attempt++ {
+		data, err := ipcFS.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var info *WindowInfo
+		err = json.Unmarshal(data, &info)
+		var syntaxErr *json.SyntaxError
+		incomplete := (err == nil && info == nil) || (errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(data)))
+		if !incomplete {
+			if err != nil {
+				return nil, fmt.Errorf("malformed metadata: %v", err)
+			}
+			return info, nil
+		}
+
+		if attempt == metaReadRetries {
+			return nil, fmt.Errorf("metadata still empty or incomplete after %d retries", metaReadRetries)
+		}
+		logDebugf("Empty or incomplete metadata in %s, retrying", filePath)
+		time.Sleep(metaReadRetryDelay)
+	}
+}
+
+// windowActionTimeout bounds how long reloadWindow and closeWindow wait for
+// the extension's acknowledgement, which may never come once the extension
+// host is torn down.
+const windowActionTimeout = 5 * time.Second
+
+// windowActionResult is the best-effort result of reloadWindow and
+// closeWindow. Acknowledged is false if the window went away or stopped
+// answering before the extension confirmed the request.
+type windowActionResult struct {
+	WindowID     string `json:"windowId"`
+	Action       string `json:"action"`
+	Acknowledged bool   `json:"acknowledged"`
+}
+
+// handleWindowAction sends reloadWindow or closeWindow to the target window.
+// Both tear down the extension host, so once the command is written the
+// window's files disappearing or the extension not answering in time count as
+// success rather than an error. Failing to write the command is an error.
+func handleWindowAction(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	toolName := request.Params.Name
+	windowIdStr := windowIdArg(request.GetArguments())
+	if allWindows, _ := request.GetArguments()["allWindows"].(bool); allWindows {
+		return nil, fmt.Errorf("%s does not support allWindows, pass each window's windowId", toolName)
+	}
+	if err := validateSchema(toolName, request.GetArguments()); err != nil {
+		return nil, err
+	}
+	windowId, err := getTargetWindow(ctx, &windowIdStr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := windowActionResult{WindowID: windowId, Action: toolName}
+	response, err := writeCommand(windowId, newCommand(toolName, json.RawMessage("{}")), windowActionTimeout)
+	switch {
+	case errors.Is(err, errNoResponse) || errors.Is(err, errExtensionGone):
+		logInfof("No acknowledgement of %s from window %s, assuming it went ahead: %v", toolName, windowId, err)
+		result.Acknowledged = false
+	case err != nil:
+		return nil, fmt.Errorf("failed to execute %s: %v", toolName, err)
+	case !response.Success:
+		return nil, fmt.Errorf("%s failed: %s", toolName, response.Error)
+	default:
+		result.Acknowledged = true
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s result: %v", toolName, err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
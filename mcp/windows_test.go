@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestGetTargetWindow(t *testing.T) {
+	type window struct {
+		id  string
+		age time.Duration
+	}
+	tests := []struct {
+		name     string
+		windows  []window
+		windowId string
+		// defaultWindow is recorded as the default window if set
+		defaultWindow string
+		want          string
+		wantErr       []string
+	}{
+		{
+			name:    "single window is used by default",
+			windows: []window{{id: "window-1"}},
+			want:    "window-1",
+		},
+		{
+			name:     "single window by ID",
+			windows:  []window{{id: "window-1"}},
+			windowId: "window-1",
+			want:     "window-1",
+		},
+		{
+			name:    "multiple windows need an ID",
+			windows: []window{{id: "window-1"}, {id: "window-2"}},
+			wantErr: []string{"multiple VS Code windows found", "- window-1: workspace-window-1", "- window-2: workspace-window-2"},
+		},
+		{
+			name:     "multiple windows by ID",
+			windows:  []window{{id: "window-1"}, {id: "window-2"}},
+			windowId: "window-2",
+			want:     "window-2",
+		},
+		{
+			name:     "unknown ID",
+			windows:  []window{{id: "window-1"}, {id: "window-2"}},
+			windowId: "window-3",
+			wantErr:  []string{"window with ID 'window-3' not found", "Active windows: 2"},
+		},
+		{
+			name:          "default window among multiple",
+			windows:       []window{{id: "window-1"}, {id: "window-2"}},
+			defaultWindow: "window-2",
+			want:          "window-2",
+		},
+		{
+			name:          "ID wins over default window",
+			windows:       []window{{id: "window-1"}, {id: "window-2"}},
+			windowId:      "window-1",
+			defaultWindow: "window-2",
+			want:          "window-1",
+		},
+		{
+			name:          "inactive default window is ignored",
+			windows:       []window{{id: "window-1"}, {id: "window-2", age: 2 * staleThreshold}, {id: "window-3"}},
+			defaultWindow: "window-2",
+			wantErr:       []string{"multiple VS Code windows found", "setDefaultWindow"},
+		},
+		{
+			name:    "no windows",
+			wantErr: []string{"no VS Code windows found"},
+		},
+		{
+			name:    "stale windows are ignored",
+			windows: []window{{id: "window-1", age: 2 * staleThreshold}, {id: "window-2"}},
+			want:    "window-2",
+		},
+		{
+			name:     "stale window by ID",
+			windows:  []window{{id: "window-1", age: 2 * staleThreshold}},
+			windowId: "window-1",
+			wantErr:  []string{"window with ID 'window-1' not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupIPC(t, nil)
+			for _, w := range tt.windows {
+				addWindow(t, w.id, "workspace-"+w.id, w.age)
+			}
+			if tt.defaultWindow != "" {
+				if err := os.WriteFile(defaultWindowFile(), []byte(tt.defaultWindow+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			windowId := tt.windowId
+			got, err := getTargetWindow(context.Background(), &windowId)
+
+			if len(tt.wantErr) > 0 {
+				if err == nil {
+					t.Fatalf("getTargetWindow() = %q, want error", got)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("getTargetWindow() error = %q, want it to contain %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getTargetWindow() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("getTargetWindow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// rewritingFS is a fileSystem that catches metadata files mid-rewrite: the
+// first reads of each one return the given partial content.
+type rewritingFS struct {
+	fileSystem
+	partial string
+	reads   int
+	calls   map[string]int
+}
+
+func (f *rewritingFS) ReadFile(name string) ([]byte, error) {
+	if strings.HasSuffix(name, ".meta.json") && f.calls[name] < f.reads {
+		f.calls[name]++
+		return []byte(f.partial), nil
+	}
+	return f.fileSystem.ReadFile(name)
+}
+
+func TestGetTargetWindowDuringHeartbeat(t *testing.T) {
+	tests := []struct {
+		name    string
+		partial string
+		reads   int
+		wantErr bool
+	}{
+		{name: "empty file", partial: "", reads: 1},
+		{name: "JSON null", partial: "null", reads: 2},
+		{name: "cut off mid-write", partial: `{"workspace": "proj`, reads: metaReadRetries},
+		{name: "still empty after retries", partial: "", reads: metaReadRetries + 1, wantErr: true},
+		{name: "malformed", partial: `{"workspace": 1}`, reads: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupIPC(t, nil)
+			addWindow(t, "window-1", "project", 0)
+			fs := &rewritingFS{fileSystem: ipcFS, partial: tt.partial, reads: tt.reads, calls: make(map[string]int)}
+			ipcFS = fs
+
+			windowId := ""
+			got, err := getTargetWindow(context.Background(), &windowId)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("getTargetWindow() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getTargetWindow() error = %v", err)
+			}
+			if got != "window-1" {
+				t.Errorf("getTargetWindow() = %q, want %q", got, "window-1")
+			}
+		})
+	}
+}
+
+// failingAppendFS is a fileSystem on which commands can't be appended to
+// command files.
+type failingAppendFS struct {
+	fileSystem
+}
+
+func (f failingAppendFS) OpenFile(name string, flag int, perm os.FileMode) (writableFile, error) {
+	if strings.HasSuffix(name, ".in") {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.fileSystem.OpenFile(name, flag, perm)
+}
+
+func TestHandleWindowAction(t *testing.T) {
+	tests := []struct {
+		name             string
+		respond          func(cmd Command) []string
+		failAppend       bool
+		wantErr          string
+		wantAcknowledged bool
+	}{
+		{
+			name: "acknowledged",
+			respond: func(cmd Command) []string {
+				return []string{responseLine(CommandResponse{ID: cmd.ID, Success: true})}
+			},
+			wantAcknowledged: true,
+		},
+		{
+			name: "window closes before answering",
+			respond: func(cmd Command) []string {
+				os.Remove(filepath.Join(vsClaudeDir, "window-1.out"))
+				return nil
+			},
+		},
+		{
+			name: "extension reports failure",
+			respond: func(cmd Command) []string {
+				return []string{responseLine(CommandResponse{ID: cmd.ID, Error: "no window"})}
+			},
+			wantErr: "closeWindow failed: no window",
+		},
+		{
+			name:       "command can't be appended",
+			failAppend: true,
+			wantErr:    "failed to execute closeWindow: failed to open command file",
+		},
+	}
+
+	registerSchemas()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupIPC(t, tt.respond)
+			addWindow(t, "window-1", "project", 0)
+			if tt.failAppend {
+				ipcFS = failingAppendFS{ipcFS}
+			}
+
+			request := mcp.CallToolRequest{}
+			request.Params.Name = "closeWindow"
+			request.Params.Arguments = map[string]any{"windowId": "window-1"}
+			result, err := handleWindowAction(context.Background(), request)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("handleWindowAction() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handleWindowAction() error = %v", err)
+			}
+
+			var got windowActionResult
+			if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Acknowledged != tt.wantAcknowledged {
+				t.Errorf("Acknowledged = %v, want %v", got.Acknowledged, tt.wantAcknowledged)
+			}
+		})
+	}
+}
@@ -1,15 +1,231 @@
+import * as vscode from 'vscode';
 import { logger } from './logger';
+import { backupDiff, type BackupDiffRequest } from './tools/backup-tools';
+import { getConfig, type GetConfigRequest, setConfig, type SetConfigRequest } from './tools/config-tools';
+import {
+	clearBreakpoint,
+	type ClearBreakpointRequest,
+	debugStart,
+	type DebugStartRequest,
+	listBreakpoints,
+	type ListBreakpointsRequest,
+	setBreakpoint,
+	type SetBreakpointRequest,
+} from './tools/debug-tools';
+import {
+	getDiagnostics,
+	type GetDiagnosticsRequest,
+	showProblems,
+	watchDiagnostics,
+	type WatchDiagnosticsRequest,
+} from './tools/diagnostics-tools';
+import {
+	getActiveEditor,
+	getFileContent,
+	type GetFileContentRequest,
+	getSelection,
+	rulers,
+	type RulersRequest,
+	saveAndClose,
+	type SaveAndCloseRequest,
+	setSelection,
+	type SetSelectionRequest,
+} from './tools/editor-tools';
+import { applyEdit, type ApplyEditRequest, moveFile, type MoveFileRequest } from './tools/edits';
+import {
+	fileHistoryDiff,
+	type FileHistoryDiffRequest,
+	getGitStatus,
+	type GetGitStatusRequest,
+	getLocationRef,
+	gitBlame,
+	type GitBlameRequest,
+	gitLog,
+	type GitLogRequest,
+	gitRestore,
+	type GitRestoreRequest,
+	gitStash,
+	gitStashList,
+	type GitStashListRequest,
+	type GitStashRequest,
+	openCommit,
+	type OpenCommitRequest,
+	openScm,
+	type OpenScmRequest,
+} from './tools/git-tools';
+import {
+	codeActions,
+	type CodeActionsRequest,
+	findReferences,
+	type FindReferencesRequest,
+	formatDocument,
+	type FormatDocumentRequest,
+	getBreadcrumbs,
+	getHover,
+	goToDefinition,
+	type GoToDefinitionRequest,
+	openDefinitionBeside,
+	organizeImports,
+	type OrganizeImportsRequest,
+	type PositionRequest,
+	type RenameRequest,
+	renameSymbol,
+	resolveImport,
+	type ResolveImportRequest,
+} from './tools/language-tools';
 import { OpenHandler } from './tools/open-tool';
-import type { OpenRequest } from './tools/types';
+import { listTodos, type ListTodosRequest, search, type SearchRequest } from './tools/search-tools';
+import { runScript, type RunScriptRequest, terminal, type TerminalRequest } from './tools/terminal-tools';
+import type { OpenRequest, PartialResponder, ToolResult } from './tools/types';
+import {
+	closeWindow,
+	presentationMode,
+	type PresentationModeRequest,
+	reloadWindow,
+	showCommands,
+	type ShowCommandsRequest,
+	showMessage,
+	type ShowMessageRequest,
+} from './tools/window-tools';
+import { getWorkspaceFolders, listExtensions, type ListExtensionsRequest } from './tools/workspace-tools';
 
 // Discriminated union for typed commands
-export type TypedCommand = { id: string; tool: 'open'; args: OpenRequest[] };
+export type TypedCommand =
+	| { id: string; tool: 'open'; args: OpenRequest[] }
+	| { id: string; tool: 'ping'; args: unknown }
+	| { id: string; tool: 'capabilities'; args: unknown }
+	| { id: string; tool: 'focusWindow'; args: unknown }
+	| { id: string; tool: 'listCommands'; args: ListCommandsRequest }
+	| { id: string; tool: 'getActiveEditor'; args: unknown }
+	| { id: string; tool: 'getConfig'; args: GetConfigRequest }
+	| { id: string; tool: 'backupDiff'; args: BackupDiffRequest }
+	| { id: string; tool: 'setConfig'; args: SetConfigRequest }
+	| { id: string; tool: 'listExtensions'; args: ListExtensionsRequest }
+	| { id: string; tool: 'presentationMode'; args: PresentationModeRequest }
+	| { id: string; tool: 'getBreadcrumbs'; args: PositionRequest }
+	| { id: string; tool: 'openScm'; args: OpenScmRequest }
+	| { id: string; tool: 'fileHistoryDiff'; args: FileHistoryDiffRequest }
+	| { id: string; tool: 'runScript'; args: RunScriptRequest }
+	| { id: string; tool: 'getDiagnostics'; args: GetDiagnosticsRequest }
+	| { id: string; tool: 'showCommands'; args: ShowCommandsRequest }
+	| { id: string; tool: 'search'; args: SearchRequest }
+	| { id: string; tool: 'resolveImport'; args: ResolveImportRequest }
+	| { id: string; tool: 'getFileContent'; args: GetFileContentRequest }
+	| { id: string; tool: 'goToDefinition'; args: GoToDefinitionRequest }
+	| { id: string; tool: 'findReferences'; args: FindReferencesRequest }
+	| { id: string; tool: 'showProblems'; args: unknown }
+	| { id: string; tool: 'rename'; args: RenameRequest }
+	| { id: string; tool: 'rulers'; args: RulersRequest }
+	| { id: string; tool: 'organizeImports'; args: OrganizeImportsRequest }
+	| { id: string; tool: 'gitStashList'; args: GitStashListRequest }
+	| { id: string; tool: 'gitStash'; args: GitStashRequest }
+	| { id: string; tool: 'showMessage'; args: ShowMessageRequest }
+	| { id: string; tool: 'openDefinitionBeside'; args: PositionRequest }
+	| { id: string; tool: 'terminal'; args: TerminalRequest }
+	| { id: string; tool: 'getLocationRef'; args: unknown }
+	| { id: string; tool: 'listTodos'; args: ListTodosRequest }
+	| { id: string; tool: 'gitLog'; args: GitLogRequest }
+	| { id: string; tool: 'openCommit'; args: OpenCommitRequest }
+	| { id: string; tool: 'gitRestore'; args: GitRestoreRequest }
+	| { id: string; tool: 'gitBlame'; args: GitBlameRequest }
+	| { id: string; tool: 'saveAndClose'; args: SaveAndCloseRequest }
+	| { id: string; tool: 'applyEdit'; args: ApplyEditRequest }
+	| { id: string; tool: 'formatDocument'; args: FormatDocumentRequest }
+	| { id: string; tool: 'codeActions'; args: CodeActionsRequest }
+	| { id: string; tool: 'getHover'; args: PositionRequest }
+	| { id: string; tool: 'moveFile'; args: MoveFileRequest }
+	| { id: string; tool: 'watchDiagnostics'; args: WatchDiagnosticsRequest }
+	| { id: string; tool: 'getSelection'; args: unknown }
+	| { id: string; tool: 'setSelection'; args: SetSelectionRequest }
+	| { id: string; tool: 'debugStart'; args: DebugStartRequest }
+	| { id: string; tool: 'setBreakpoint'; args: SetBreakpointRequest }
+	| { id: string; tool: 'clearBreakpoint'; args: ClearBreakpointRequest }
+	| { id: string; tool: 'listBreakpoints'; args: ListBreakpointsRequest }
+	| { id: string; tool: 'getWorkspaceFolders'; args: unknown }
+	| { id: string; tool: 'closeWindow'; args: unknown }
+	| { id: string; tool: 'reloadWindow'; args: unknown }
+	| { id: string; tool: 'getGitStatus'; args: GetGitStatusRequest };
+
+interface ListCommandsRequest {
+	filter?: string;
+	includeInternal?: boolean;
+}
+
+// Tools and open item types this extension implements, reported by capabilities
+const supportedTools: TypedCommand['tool'][] = [
+	'applyEdit',
+	'backupDiff',
+	'capabilities',
+	'clearBreakpoint',
+	'closeWindow',
+	'codeActions',
+	'debugStart',
+	'fileHistoryDiff',
+	'findReferences',
+	'focusWindow',
+	'formatDocument',
+	'getActiveEditor',
+	'getBreadcrumbs',
+	'getConfig',
+	'getDiagnostics',
+	'getFileContent',
+	'getGitStatus',
+	'getHover',
+	'getLocationRef',
+	'getSelection',
+	'getWorkspaceFolders',
+	'gitBlame',
+	'gitLog',
+	'gitRestore',
+	'gitStash',
+	'gitStashList',
+	'goToDefinition',
+	'listBreakpoints',
+	'listCommands',
+	'listExtensions',
+	'listTodos',
+	'moveFile',
+	'open',
+	'openCommit',
+	'openDefinitionBeside',
+	'openScm',
+	'organizeImports',
+	'ping',
+	'presentationMode',
+	'reloadWindow',
+	'rename',
+	'resolveImport',
+	'rulers',
+	'runScript',
+	'saveAndClose',
+	'search',
+	'setBreakpoint',
+	'setConfig',
+	'setSelection',
+	'showCommands',
+	'showMessage',
+	'showProblems',
+	'terminal',
+	'watchDiagnostics',
+];
+const supportedItemTypes: OpenRequest['type'][] = [
+	'createFile',
+	'diff',
+	'file',
+	'gitDiff',
+	'insert',
+	'notebook',
+	'openFolder',
+	'reveal',
+];
 
 // Raw command from MCP (before type validation)
 export interface Command {
 	id: string;
 	tool: string;
 	args: unknown; // Raw JSON args passed through from MCP
+	// Report the changes the command would make instead of applying them
+	dryRun?: boolean;
 }
 
 export interface CommandResponse {
@@ -17,20 +233,26 @@ export interface CommandResponse {
 	success: boolean;
 	data?: unknown;
 	error?: string;
+	// Optional error classification, e.g. FILE_NOT_FOUND or UNSUPPORTED_TYPE
+	code?: string;
+	// Intermediate progress line, a final non-partial response follows
+	partial?: boolean;
 }
 
 export class CommandHandler {
 	private openHandler: OpenHandler;
+	private globalStoragePath: string;
 
-	constructor() {
+	constructor(globalStoragePath: string) {
 		this.openHandler = new OpenHandler();
+		this.globalStoragePath = globalStoragePath;
 	}
 
 	/**
 	 * Type guard to check if a command is properly typed
 	 */
 	private isTypedCommand(command: Command): command is TypedCommand {
-		return command.tool === 'open';
+		return (supportedTools as string[]).includes(command.tool);
 	}
 
 	/**
@@ -44,7 +266,10 @@ export class CommandHandler {
 		return [args as T];
 	}
 
-	async executeCommand(command: Command): Promise<{ success: boolean; data?: unknown; error?: string }> {
+	/**
+	 * Runs a command. Long-running tools send intermediate data through respond before their result.
+	 */
+	async executeCommand(command: Command, respond: PartialResponder = async () => {}): Promise<ToolResult> {
 		// Log the incoming command
 		logger.info('CommandHandler', `Received command: ${command.tool}`);
 		logger.info('CommandHandler', 'Raw JSON input:', command);
@@ -54,23 +279,267 @@ export class CommandHandler {
 			if (!this.isTypedCommand(command)) {
 				const error = `Unknown command: ${command.tool}`;
 				logger.warn('CommandHandler', error);
-				return { success: false, error };
+				return { success: false, error, code: 'UNSUPPORTED_TYPE' };
 			}
 
-			// Cast to typed command and ensure args is an array
+			// Cast to typed command, open takes an array of items and every other tool an object
 			const typedCommand: TypedCommand = {
 				...command,
-				args: this.ensureArray(command.args),
+				args: command.tool === 'open' ? this.ensureArray(command.args) : (command.args ?? {}),
 			} as TypedCommand;
 
-			let result: { success: boolean; data?: unknown; error?: string };
+			let result: ToolResult;
 
 			switch (typedCommand.tool) {
 				case 'open': {
+					if (command.dryRun) {
+						result = await this.openHandler.dryRun(typedCommand.args);
+						break;
+					}
 					// OpenHandler already accepts an array
 					result = await this.openHandler.execute(typedCommand.args);
 					break;
 				}
+				case 'ping': {
+					// Echo back liveness information, the MCP server measures the round trip
+					const extension = vscode.extensions.getExtension('mariozechner.vs-claude');
+					result = {
+						success: true,
+						data: { timestamp: new Date().toISOString(), version: extension?.packageJSON.version ?? 'unknown' },
+					};
+					break;
+				}
+				case 'focusWindow': {
+					// Raise the OS window, as far as the OS allows, then move focus to the active editor.
+					// Focusing an editor group alone only moves focus inside the window
+					const commands = await vscode.commands.getCommands();
+					const raiseRequested = commands.includes('workbench.action.focusWindow');
+					if (raiseRequested) {
+						await vscode.commands.executeCommand('workbench.action.focusWindow');
+					}
+					await vscode.commands.executeCommand('workbench.action.focusActiveEditorGroup');
+					result = {
+						success: true,
+						data: { focusRequested: true, raiseRequested, focused: vscode.window.state.focused },
+					};
+					break;
+				}
+				case 'listCommands': {
+					// The MCP server sorts and limits the IDs, only narrow them down here
+					const { filter = '', includeInternal = false } = typedCommand.args;
+					const ids = await vscode.commands.getCommands(!includeInternal);
+					const needle = filter.toLowerCase();
+					result = { success: true, data: ids.filter((id) => id.toLowerCase().includes(needle)) };
+					break;
+				}
+				case 'getActiveEditor': {
+					result = getActiveEditor();
+					break;
+				}
+				case 'getConfig': {
+					result = await getConfig(typedCommand.args);
+					break;
+				}
+				case 'backupDiff': {
+					result = await backupDiff(typedCommand.args, this.globalStoragePath);
+					break;
+				}
+				case 'setConfig': {
+					result = await setConfig(typedCommand.args);
+					break;
+				}
+				case 'listExtensions': {
+					result = listExtensions(typedCommand.args);
+					break;
+				}
+				case 'presentationMode': {
+					result = await presentationMode(typedCommand.args);
+					break;
+				}
+				case 'getBreadcrumbs': {
+					result = await getBreadcrumbs(typedCommand.args);
+					break;
+				}
+				case 'openScm': {
+					result = await openScm(typedCommand.args);
+					break;
+				}
+				case 'fileHistoryDiff': {
+					result = await fileHistoryDiff(typedCommand.args);
+					break;
+				}
+				case 'runScript': {
+					result = await runScript(typedCommand.args);
+					break;
+				}
+				case 'getDiagnostics': {
+					result = getDiagnostics(typedCommand.args);
+					break;
+				}
+				case 'showCommands': {
+					result = await showCommands(typedCommand.args);
+					break;
+				}
+				case 'search': {
+					result = await search(typedCommand.args);
+					break;
+				}
+				case 'resolveImport': {
+					result = await resolveImport(typedCommand.args);
+					break;
+				}
+				case 'getFileContent': {
+					result = getFileContent(typedCommand.args);
+					break;
+				}
+				case 'goToDefinition': {
+					result = await goToDefinition(typedCommand.args);
+					break;
+				}
+				case 'findReferences': {
+					result = await findReferences(typedCommand.args);
+					break;
+				}
+				case 'showProblems': {
+					result = await showProblems();
+					break;
+				}
+				case 'rename': {
+					result = await renameSymbol(typedCommand.args, command.dryRun === true);
+					break;
+				}
+				case 'rulers': {
+					result = await rulers(typedCommand.args);
+					break;
+				}
+				case 'organizeImports': {
+					result = await organizeImports(typedCommand.args, command.dryRun === true);
+					break;
+				}
+				case 'gitStashList': {
+					result = await gitStashList(typedCommand.args);
+					break;
+				}
+				case 'gitStash': {
+					result = await gitStash(typedCommand.args);
+					break;
+				}
+				case 'showMessage': {
+					result = await showMessage(typedCommand.args);
+					break;
+				}
+				case 'openDefinitionBeside': {
+					result = await openDefinitionBeside(typedCommand.args);
+					break;
+				}
+				case 'terminal': {
+					result = await terminal(typedCommand.args);
+					break;
+				}
+				case 'getLocationRef': {
+					result = await getLocationRef();
+					break;
+				}
+				case 'listTodos': {
+					result = await listTodos(typedCommand.args);
+					break;
+				}
+				case 'gitLog': {
+					result = await gitLog(typedCommand.args);
+					break;
+				}
+				case 'openCommit': {
+					result = await openCommit(typedCommand.args);
+					break;
+				}
+				case 'gitRestore': {
+					result = await gitRestore(typedCommand.args);
+					break;
+				}
+				case 'gitBlame': {
+					result = await gitBlame(typedCommand.args);
+					break;
+				}
+				case 'saveAndClose': {
+					result = await saveAndClose(typedCommand.args);
+					break;
+				}
+				case 'applyEdit': {
+					result = await applyEdit(typedCommand.args, command.dryRun === true);
+					break;
+				}
+				case 'formatDocument': {
+					result = await formatDocument(typedCommand.args, command.dryRun === true);
+					break;
+				}
+				case 'codeActions': {
+					result = await codeActions(typedCommand.args, command.dryRun === true);
+					break;
+				}
+				case 'getHover': {
+					result = await getHover(typedCommand.args);
+					break;
+				}
+				case 'moveFile': {
+					result = await moveFile(typedCommand.args, command.dryRun === true);
+					break;
+				}
+				case 'watchDiagnostics': {
+					result = await watchDiagnostics(typedCommand.args, respond);
+					break;
+				}
+				case 'getSelection': {
+					result = getSelection();
+					break;
+				}
+				case 'setSelection': {
+					result = await setSelection(typedCommand.args);
+					break;
+				}
+				case 'debugStart': {
+					result = await debugStart(typedCommand.args);
+					break;
+				}
+				case 'setBreakpoint': {
+					result = setBreakpoint(typedCommand.args);
+					break;
+				}
+				case 'clearBreakpoint': {
+					result = clearBreakpoint(typedCommand.args);
+					break;
+				}
+				case 'listBreakpoints': {
+					result = listBreakpoints(typedCommand.args);
+					break;
+				}
+				case 'getWorkspaceFolders': {
+					result = getWorkspaceFolders();
+					break;
+				}
+				case 'closeWindow': {
+					result = closeWindow();
+					break;
+				}
+				case 'reloadWindow': {
+					result = reloadWindow();
+					break;
+				}
+				case 'getGitStatus': {
+					result = await getGitStatus(typedCommand.args);
+					break;
+				}
+				case 'capabilities': {
+					const extension = vscode.extensions.getExtension('mariozechner.vs-claude');
+					result = {
+						success: true,
+						data: {
+							version: extension?.packageJSON.version ?? 'unknown',
+							tools: supportedTools,
+							itemTypes: supportedItemTypes,
+						},
+					};
+					break;
+				}
 			}
 
 			// Log command result
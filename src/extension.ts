@@ -12,7 +12,7 @@ let panelManager: PanelManager;
 export async function activate(context: vscode.ExtensionContext) {
 	logger.info('Extension', 'VS Claude extension activating...');
 
-	windowManager = new WindowManager();
+	windowManager = new WindowManager(context.globalStorageUri.fsPath);
 	setupManager = new SetupManager(context);
 	panelManager = new PanelManager(context);
 
@@ -0,0 +1,93 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import * as vscode from 'vscode';
+import type { ToolResult } from './types';
+
+export interface BackupDiffRequest {
+	path: string;
+}
+
+interface Backup {
+	content: string;
+	modified: Date;
+}
+
+// Backup contents shown as the right side of backup diffs, keyed by URI
+const backupScheme = 'vs-claude-backup';
+const backupContents = new Map<string, string>();
+let backupProvider: vscode.Disposable | undefined;
+let backupCounter = 0;
+
+// Returns a read-only URI with the backup's content, keeping the file name so the
+// document gets the same language
+function backupUri(filePath: string, content: string): vscode.Uri {
+	if (!backupProvider) {
+		backupProvider = vscode.workspace.registerTextDocumentContentProvider(backupScheme, {
+			provideTextDocumentContent: (uri) => backupContents.get(uri.toString()) ?? '',
+		});
+	}
+	const uri = vscode.Uri.from({ scheme: backupScheme, path: `/${++backupCounter}/${path.basename(filePath)}` });
+	backupContents.set(uri.toString(), content);
+	return uri;
+}
+
+// Lists the files in a directory, or nothing if it doesn't exist
+async function listFiles(dir: string): Promise<string[]> {
+	try {
+		return (await fs.promises.readdir(dir)).map((name) => path.join(dir, name));
+	} catch {
+		return [];
+	}
+}
+
+// Finds the newest hot exit backup of a resource. VS Code keeps backups in
+// <user data>/Backups/<workspace>/<scheme>/<hash>, next to the extension's global storage in
+// <user data>/User/globalStorage/<extension id>. Each backup starts with a line holding the
+// resource URI, optionally followed by a space and JSON metadata, and the content after it.
+async function findBackup(globalStoragePath: string, uri: vscode.Uri): Promise<Backup | undefined> {
+	const backupsDir = path.join(globalStoragePath, '..', '..', '..', 'Backups');
+	let newest: Backup | undefined;
+	for (const workspaceDir of await listFiles(backupsDir)) {
+		for (const file of await listFiles(path.join(workspaceDir, uri.scheme))) {
+			let raw: string;
+			let modified: Date;
+			try {
+				raw = await fs.promises.readFile(file, 'utf8');
+				modified = (await fs.promises.stat(file)).mtime;
+			} catch {
+				continue;
+			}
+			const preambleEnd = raw.indexOf('\n');
+			if (preambleEnd === -1) continue;
+			const resource = raw.slice(0, preambleEnd).split(' ')[0];
+			if (resource !== uri.toString()) continue;
+			if (!newest || modified > newest.modified) {
+				newest = { content: raw.slice(preambleEnd + 1), modified };
+			}
+		}
+	}
+	return newest;
+}
+
+/**
+ * Opens a diff between a file on disk and VS Code's hot exit backup of it, if there is one.
+ */
+export async function backupDiff(
+	{ path: filePath }: BackupDiffRequest,
+	globalStoragePath: string
+): Promise<ToolResult> {
+	const uri = vscode.Uri.file(filePath);
+	const backup = await findBackup(globalStoragePath, uri);
+	if (!backup) {
+		return { success: true, data: { backup: false, message: `no backup available for ${filePath}` } };
+	}
+	if (!fs.existsSync(filePath)) {
+		return { success: false, error: `File not found: ${filePath}` };
+	}
+
+	const timestamp = backup.modified.toISOString();
+	const title = `${path.basename(filePath)} (disk ↔ backup from ${timestamp})`;
+	const right = backupUri(filePath, backup.content);
+	await vscode.commands.executeCommand('vscode.diff', uri, right, title, { preview: false });
+	return { success: true, data: { backup: true, timestamp } };
+}
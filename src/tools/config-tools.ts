@@ -0,0 +1,81 @@
+import * as vscode from 'vscode';
+import type { ToolResult } from './types';
+
+export interface GetConfigRequest {
+	key: string;
+	path?: string;
+}
+
+type InspectField =
+	| 'defaultValue'
+	| 'globalValue'
+	| 'workspaceValue'
+	| 'workspaceFolderValue'
+	| 'defaultLanguageValue'
+	| 'globalLanguageValue'
+	| 'workspaceLanguageValue'
+	| 'workspaceFolderLanguageValue';
+
+// inspect() fields from the lowest to the highest precedence, with the scope they are reported as
+const configScopes: Array<[InspectField, string]> = [
+	['defaultValue', 'default'],
+	['globalValue', 'user'],
+	['workspaceValue', 'workspace'],
+	['workspaceFolderValue', 'workspaceFolder'],
+	['defaultLanguageValue', 'defaultLanguage'],
+	['globalLanguageValue', 'userLanguage'],
+	['workspaceLanguageValue', 'workspaceLanguage'],
+	['workspaceFolderLanguageValue', 'workspaceFolderLanguage'],
+];
+
+/**
+ * Reports the effective value of a setting and the scope it comes from, optionally for a file,
+ * so its folder and language overrides apply.
+ */
+export async function getConfig({ key, path }: GetConfigRequest): Promise<ToolResult> {
+	let scope: vscode.ConfigurationScope | undefined;
+	if (path) {
+		// The language of the file selects language-specific overrides
+		const document = await vscode.workspace.openTextDocument(vscode.Uri.file(path));
+		scope = { uri: document.uri, languageId: document.languageId };
+	}
+
+	const configuration = vscode.workspace.getConfiguration(undefined, scope);
+	const inspected = configuration.inspect(key);
+	let source = 'none';
+	for (const [field, name] of configScopes) {
+		if (inspected?.[field] !== undefined) {
+			source = name;
+		}
+	}
+
+	// get() merges object values across scopes the way the editor does
+	const value = source === 'none' ? null : configuration.get(key);
+	return { success: true, data: { key, value: value ?? null, scope: source } };
+}
+
+export interface SetConfigRequest {
+	key: string;
+	value: unknown;
+	target: 'workspace' | 'user';
+}
+
+/**
+ * Writes a setting to the user or workspace settings and reports the value it replaced there.
+ */
+export async function setConfig({ key, value, target }: SetConfigRequest): Promise<ToolResult> {
+	if (target === 'workspace' && !vscode.workspace.workspaceFolders?.length) {
+		return { success: false, error: 'Workspace settings require an open folder or workspace' };
+	}
+
+	const configuration = vscode.workspace.getConfiguration();
+	const inspected = configuration.inspect(key);
+	const previousValue = (target === 'user' ? inspected?.globalValue : inspected?.workspaceValue) ?? null;
+
+	// undefined removes the setting from the target's settings file
+	const configurationTarget =
+		target === 'user' ? vscode.ConfigurationTarget.Global : vscode.ConfigurationTarget.Workspace;
+	await configuration.update(key, value === null ? undefined : value, configurationTarget);
+
+	return { success: true, data: { key, target, previousValue, value } };
+}
@@ -0,0 +1,133 @@
+import * as vscode from 'vscode';
+import type { ToolResult } from './types';
+
+export interface DebugStartRequest {
+	config: string | vscode.DebugConfiguration;
+	folder?: string;
+	noDebug?: boolean;
+}
+
+// Returns the names of the launch configurations and compounds visible to a folder, or to the workspace
+function launchNames(scope: vscode.WorkspaceFolder | undefined): string[] {
+	const launch = vscode.workspace.getConfiguration('launch', scope?.uri);
+	const entries = [
+		...(launch.get<Array<{ name?: unknown }>>('configurations') ?? []),
+		...(launch.get<Array<{ name?: unknown }>>('compounds') ?? []),
+	];
+	return entries.map((entry) => entry.name).filter((name): name is string => typeof name === 'string');
+}
+
+/**
+ * Starts a debug session from a named launch configuration or an inline one.
+ */
+export async function debugStart({ config, folder, noDebug }: DebugStartRequest): Promise<ToolResult> {
+	let workspaceFolder: vscode.WorkspaceFolder | undefined;
+	if (folder) {
+		workspaceFolder = vscode.workspace.workspaceFolders?.find((candidate) => candidate.uri.fsPath === folder);
+		if (!workspaceFolder) {
+			return { success: false, error: `Not a workspace folder: ${folder}` };
+		}
+	}
+
+	if (typeof config === 'string') {
+		// Without a folder, look in every folder's launch.json, then in the workspace's launch settings
+		const scopes = workspaceFolder ? [workspaceFolder] : [...(vscode.workspace.workspaceFolders ?? []), undefined];
+		const index = scopes.findIndex((scope) => launchNames(scope).includes(config));
+		if (index === -1) {
+			const available = [...new Set(scopes.flatMap(launchNames))].join(', ') || 'none';
+			return { success: false, error: `Launch configuration not found: ${config}. Available: ${available}` };
+		}
+		workspaceFolder = scopes[index];
+	}
+
+	// The session that starts first during the call is the one reported, a compound's first member
+	let session: vscode.DebugSession | undefined;
+	const listener = vscode.debug.onDidStartDebugSession((started) => {
+		session ??= started;
+	});
+	let started: boolean;
+	try {
+		started = await vscode.debug.startDebugging(workspaceFolder, config, { noDebug: noDebug === true });
+	} finally {
+		listener.dispose();
+	}
+
+	const name = typeof config === 'string' ? config : config.name;
+	return {
+		success: true,
+		data: { started, name, type: session?.type ?? null, sessionId: session?.id ?? null },
+	};
+}
+
+// Returns the source breakpoints in a file, optionally only those on a 1-based line
+function sourceBreakpoints(path: string, line?: number): vscode.SourceBreakpoint[] {
+	return vscode.debug.breakpoints.filter(
+		(breakpoint): breakpoint is vscode.SourceBreakpoint =>
+			breakpoint instanceof vscode.SourceBreakpoint &&
+			breakpoint.location.uri.scheme === 'file' &&
+			breakpoint.location.uri.fsPath === path &&
+			(line === undefined || breakpoint.location.range.start.line === line - 1)
+	);
+}
+
+// Converts a breakpoint to the shape reported to the MCP server, with the optional fields only when set
+function toBreakpointInfo(breakpoint: vscode.Breakpoint) {
+	const location =
+		breakpoint instanceof vscode.SourceBreakpoint
+			? { path: breakpoint.location.uri.fsPath, line: breakpoint.location.range.start.line + 1 }
+			: breakpoint instanceof vscode.FunctionBreakpoint
+				? { function: breakpoint.functionName }
+				: {};
+	return {
+		...location,
+		enabled: breakpoint.enabled,
+		...(breakpoint.condition ? { condition: breakpoint.condition } : {}),
+		...(breakpoint.hitCondition ? { hitCondition: breakpoint.hitCondition } : {}),
+		...(breakpoint.logMessage ? { logMessage: breakpoint.logMessage } : {}),
+	};
+}
+
+export interface SetBreakpointRequest {
+	path: string;
+	line: number;
+	condition?: string;
+	hitCondition?: string;
+	logMessage?: string;
+}
+
+/**
+ * Adds a source breakpoint on a line, replacing one already there.
+ */
+export function setBreakpoint({ path, line, condition, hitCondition, logMessage }: SetBreakpointRequest): ToolResult {
+	vscode.debug.removeBreakpoints(sourceBreakpoints(path, line));
+	const location = new vscode.Location(vscode.Uri.file(path), new vscode.Position(line - 1, 0));
+	const breakpoint = new vscode.SourceBreakpoint(location, true, condition, hitCondition, logMessage);
+	vscode.debug.addBreakpoints([breakpoint]);
+	return { success: true, data: toBreakpointInfo(breakpoint) };
+}
+
+export interface ClearBreakpointRequest {
+	path: string;
+	line?: number;
+}
+
+/**
+ * Removes the source breakpoints on a line, or in a whole file.
+ */
+export function clearBreakpoint({ path, line }: ClearBreakpointRequest): ToolResult {
+	const breakpoints = sourceBreakpoints(path, line);
+	vscode.debug.removeBreakpoints(breakpoints);
+	return { success: true, data: { removed: breakpoints.length } };
+}
+
+export interface ListBreakpointsRequest {
+	path?: string;
+}
+
+/**
+ * Lists the breakpoints, optionally only the source breakpoints in a file.
+ */
+export function listBreakpoints({ path }: ListBreakpointsRequest): ToolResult {
+	const breakpoints = path ? sourceBreakpoints(path) : vscode.debug.breakpoints;
+	return { success: true, data: breakpoints.map(toBreakpointInfo) };
+}
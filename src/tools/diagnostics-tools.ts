@@ -0,0 +1,109 @@
+import * as vscode from 'vscode';
+import { toLineRange } from './positions';
+import type { PartialResponder, ToolResult } from './types';
+
+type Severity = 'error' | 'warning' | 'information' | 'hint';
+
+// Indexed by vscode.DiagnosticSeverity
+const severities: Severity[] = ['error', 'warning', 'information', 'hint'];
+
+export interface DiagnosticsFilter {
+	source?: string;
+	severity?: Severity;
+}
+
+// Converts a diagnostic to the shape reported to the MCP server
+function toDiagnosticInfo(diagnostic: vscode.Diagnostic) {
+	return {
+		severity: severities[diagnostic.severity],
+		source: diagnostic.source ?? '',
+		message: diagnostic.message,
+		range: toLineRange(diagnostic.range),
+	};
+}
+
+// Reports a file's diagnostics matching the filter, and every source present regardless of it
+function describeDiagnostics(uri: vscode.Uri, { source, severity }: DiagnosticsFilter) {
+	const all = vscode.languages.getDiagnostics(uri);
+	const sources = [...new Set(all.map((diagnostic) => diagnostic.source ?? '').filter((name) => name))].sort();
+	const diagnostics = all
+		.filter((diagnostic) => source === undefined || diagnostic.source === source)
+		.filter((diagnostic) => severity === undefined || severities[diagnostic.severity] === severity)
+		.map(toDiagnosticInfo);
+	return { path: uri.fsPath, sources, diagnostics };
+}
+
+export interface GetDiagnosticsRequest extends DiagnosticsFilter {
+	path?: string;
+}
+
+/**
+ * Reports the diagnostics of a file, by default the active editor's, optionally filtered by source and severity.
+ */
+export function getDiagnostics({ path, ...filter }: GetDiagnosticsRequest): ToolResult {
+	const uri = path ? vscode.Uri.file(path) : vscode.window.activeTextEditor?.document.uri;
+	if (!uri) {
+		return { success: false, error: 'No active editor, pass a path' };
+	}
+	return { success: true, data: describeDiagnostics(uri, filter) };
+}
+
+/**
+ * Reveals the Problems panel and counts the workspace's diagnostics by severity.
+ */
+export async function showProblems(): Promise<ToolResult> {
+	const counts: Record<Severity, number> = { error: 0, warning: 0, information: 0, hint: 0 };
+	for (const [, diagnostics] of vscode.languages.getDiagnostics()) {
+		for (const diagnostic of diagnostics) {
+			counts[severities[diagnostic.severity]]++;
+		}
+	}
+	// The view's generated focus command, workbench.actions.view.problems would hide an already focused panel
+	await vscode.commands.executeCommand('workbench.panel.markers.view.focus');
+	return { success: true, data: { shown: true, counts } };
+}
+
+export interface WatchDiagnosticsRequest extends DiagnosticsFilter {
+	path: string;
+	duration?: number;
+	untilClean?: boolean;
+}
+
+/**
+ * Sends a file's diagnostics as partial responses, first as they are and then on every change, until
+ * the duration has passed or, with untilClean, none match the filter anymore.
+ */
+export async function watchDiagnostics(
+	{ path, duration = 30, untilClean, ...filter }: WatchDiagnosticsRequest,
+	respond: PartialResponder
+): Promise<ToolResult> {
+	const uri = vscode.Uri.file(path);
+	// Snapshots are written in order, the final response may only follow once all of them are
+	let written = Promise.resolve();
+	const snapshot = (): boolean => {
+		const described = describeDiagnostics(uri, filter);
+		written = written.then(() => respond(described));
+		return described.diagnostics.length === 0;
+	};
+
+	await new Promise<void>((resolve) => {
+		if (snapshot() && untilClean) {
+			resolve();
+			return;
+		}
+		const finish = () => {
+			clearTimeout(timer);
+			listener.dispose();
+			resolve();
+		};
+		const timer = setTimeout(finish, duration * 1000);
+		const listener = vscode.languages.onDidChangeDiagnostics((event) => {
+			if (event.uris.some((changed) => changed.toString() === uri.toString()) && snapshot() && untilClean) {
+				finish();
+			}
+		});
+	});
+	await written;
+	// The snapshots are the result, the MCP server combines the partial responses' data
+	return { success: true };
+}
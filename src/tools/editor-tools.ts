@@ -0,0 +1,229 @@
+import * as fs from 'fs';
+import * as vscode from 'vscode';
+import { toRevealType } from './open-tool';
+import { fromEncodedPosition, toEncodedCharacter, toLineRange } from './positions';
+import type { OffsetEncoding, ToolResult } from './types';
+
+/**
+ * Reports the file, selection and visible lines of the active text editor.
+ */
+export function getActiveEditor(): ToolResult {
+	const editor = vscode.window.activeTextEditor;
+	if (!editor) {
+		return { success: true, data: { active: null } };
+	}
+
+	const document = editor.document;
+	const visible = editor.visibleRanges[0];
+	return {
+		success: true,
+		data: {
+			active: {
+				path: document.uri.scheme === 'file' ? document.uri.fsPath : document.uri.toString(),
+				languageId: document.languageId,
+				selection: toLineRange(editor.selection),
+				visibleRange: visible ? { startLine: visible.start.line + 1, endLine: visible.end.line + 1 } : null,
+			},
+		},
+	};
+}
+
+/**
+ * Reports the text of each selection in the active text editor, the primary selection first.
+ */
+export function getSelection(): ToolResult {
+	const editor = vscode.window.activeTextEditor;
+	if (!editor) {
+		return { success: true, data: [] };
+	}
+	const uri = editor.document.uri;
+	return {
+		success: true,
+		data: editor.selections.map((selection) => ({
+			path: uri.scheme === 'file' ? uri.fsPath : uri.toString(),
+			text: editor.document.getText(selection),
+			range: toLineRange(selection),
+			empty: selection.isEmpty,
+		})),
+	};
+}
+
+export interface GetFileContentRequest {
+	path: string;
+	startLine?: number;
+	endLine?: number;
+}
+
+/**
+ * Reads a file as the user sees it: the editor buffer if it has unsaved changes, the file on disk otherwise.
+ */
+export function getFileContent({ path, startLine, endLine }: GetFileContentRequest): ToolResult {
+	const document = vscode.workspace.textDocuments.find(
+		(candidate) => candidate.uri.scheme === 'file' && candidate.uri.fsPath === path
+	);
+	const dirty = document?.isDirty === true;
+	const text = dirty && document ? document.getText() : fs.readFileSync(path, 'utf8');
+
+	// Lines keep a \r of CRLF line endings, so joining them restores the content
+	const lines = text.split('\n');
+	const content =
+		startLine === undefined && endLine === undefined
+			? text
+			: lines.slice((startLine ?? 1) - 1, endLine ?? lines.length).join('\n');
+
+	return {
+		success: true,
+		data: {
+			path,
+			source: dirty ? 'buffer' : 'disk',
+			version: dirty && document ? document.version : null,
+			dirty,
+			lineCount: lines.length,
+			content,
+		},
+	};
+}
+
+export interface RulersRequest {
+	path: string;
+	columns?: number[];
+	clear?: boolean;
+}
+
+/**
+ * Shows or clears ruler guides for a file's language in the workspace settings, opening the file.
+ */
+export async function rulers({ path, columns, clear }: RulersRequest): Promise<ToolResult> {
+	if (!vscode.workspace.workspaceFolders?.length) {
+		return { success: false, error: 'Rulers are set in workspace settings, which require an open folder' };
+	}
+
+	const document = await vscode.workspace.openTextDocument(vscode.Uri.file(path));
+	await vscode.window.showTextDocument(document, { preview: false });
+
+	const configuration = vscode.workspace.getConfiguration('editor', { languageId: document.languageId });
+	// undefined removes the language-specific value, so rulers from other scopes show again
+	await configuration.update('rulers', clear ? undefined : columns, vscode.ConfigurationTarget.Workspace, true);
+	return { success: true, data: { path, columns: clear ? [] : columns } };
+}
+
+export interface SaveAndCloseRequest {
+	paths?: string[];
+	all?: boolean;
+}
+
+// Returns the file a tab shows, the modified side for diffs
+function tabUri(tab: vscode.Tab): vscode.Uri | undefined {
+	const input = tab.input;
+	if (input instanceof vscode.TabInputTextDiff) {
+		return input.modified;
+	}
+	if (
+		input instanceof vscode.TabInputText ||
+		input instanceof vscode.TabInputCustom ||
+		input instanceof vscode.TabInputNotebook
+	) {
+		return input.uri;
+	}
+	return undefined;
+}
+
+/**
+ * Saves the files with unsaved changes, then closes their editors. Files that fail to save stay open.
+ */
+export async function saveAndClose({ paths, all }: SaveAndCloseRequest): Promise<ToolResult> {
+	const tabsByPath = new Map<string, vscode.Tab[]>();
+	const otherTabs: vscode.Tab[] = [];
+	for (const tab of vscode.window.tabGroups.all.flatMap((group) => group.tabs)) {
+		const uri = tabUri(tab);
+		if (uri?.scheme !== 'file') {
+			otherTabs.push(tab);
+			continue;
+		}
+		if (all || paths?.includes(uri.fsPath)) {
+			tabsByPath.set(uri.fsPath, [...(tabsByPath.get(uri.fsPath) ?? []), tab]);
+		}
+	}
+
+	const closed: string[] = [];
+	const saved: string[] = [];
+	const failed: Array<{ path: string; error: string }> = [];
+	for (const [path, tabs] of tabsByPath) {
+		if (tabs.some((tab) => tab.isDirty)) {
+			const document = vscode.workspace.textDocuments.find(
+				(candidate) => candidate.uri.scheme === 'file' && candidate.uri.fsPath === path
+			);
+			const error = document
+				? await document.save().then(
+						(ok) => (ok ? undefined : 'Save failed'),
+						(reason) => String(reason)
+					)
+				: 'Unsaved changes are not in a text document and cannot be saved';
+			if (error) {
+				failed.push({ path, error });
+				continue;
+			}
+			saved.push(path);
+		}
+		await vscode.window.tabGroups.close(tabs);
+		closed.push(path);
+	}
+
+	// Editors without a file, like webviews, are closed with all unless they would prompt
+	if (all) {
+		await vscode.window.tabGroups.close(otherTabs.filter((tab) => !tab.isDirty));
+	}
+	return { success: true, data: { closed, saved, failed } };
+}
+
+interface SelectionPosition {
+	line: number;
+	character: number;
+}
+
+export interface SetSelectionRequest {
+	path: string;
+	selections: Array<{ start: SelectionPosition; end: SelectionPosition }>;
+	reveal?: boolean | 'center' | 'top' | 'centerIfOutsideViewport';
+	offsetEncoding?: OffsetEncoding;
+}
+
+/**
+ * Opens a file and sets its selections, the first one primary, optionally scrolling it into view.
+ */
+export async function setSelection({
+	path,
+	selections,
+	reveal = false,
+	offsetEncoding,
+}: SetSelectionRequest): Promise<ToolResult> {
+	const document = await vscode.workspace.openTextDocument(vscode.Uri.file(path));
+	const editor = await vscode.window.showTextDocument(document, { preview: false });
+
+	let clamped = false;
+	const toPosition = ({ line, character }: SelectionPosition) => {
+		const requested = fromEncodedPosition(document, line, character, offsetEncoding);
+		const valid = document.validatePosition(requested);
+		clamped ||= !valid.isEqual(requested);
+		return valid;
+	};
+	editor.selections = selections.map(({ start, end }) => new vscode.Selection(toPosition(start), toPosition(end)));
+	if (reveal !== false) {
+		editor.revealRange(editor.selection, toRevealType(reveal === true ? 'center' : reveal));
+	}
+
+	// Anchors and cursors, unlike ranges, keep the direction of the selections
+	return {
+		success: true,
+		data: {
+			path,
+			selections: editor.selections.map(({ anchor, active }) => ({
+				startLine: anchor.line + 1,
+				startCharacter: toEncodedCharacter(document, anchor, offsetEncoding),
+				endLine: active.line + 1,
+				endCharacter: toEncodedCharacter(document, active, offsetEncoding),
+			})),
+			clamped,
+		},
+	};
+}
@@ -0,0 +1,189 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import * as vscode from 'vscode';
+import { fromEncodedLineRange, toEncodedLineRange, toLineRange } from './positions';
+import type { EolMode, LineRange, OffsetEncoding, ToolResult } from './types';
+
+// A file's text edits as reported by dry runs
+export interface FileChange {
+	path: string;
+	edits: Array<{ range: LineRange; newText: string }>;
+}
+
+// Returns a file URI's path, or the URI itself for other schemes
+export function displayPath(uri: vscode.Uri): string {
+	return uri.scheme === 'file' ? uri.fsPath : uri.toString();
+}
+
+/**
+ * Lists the text edits of a workspace edit per file, in the shape dry runs report them.
+ */
+export function workspaceEditChanges(edit: vscode.WorkspaceEdit): FileChange[] {
+	return edit.entries().map(([uri, edits]) => ({
+		path: displayPath(uri),
+		edits: reportedEdits(edits).map((textEdit) => ({
+			range: toLineRange(textEdit.range),
+			newText: textEdit.newText,
+		})),
+	}));
+}
+
+// Line ending changes come as edits with an empty range, they have no text to show
+function reportedEdits(edits: vscode.TextEdit[]): vscode.TextEdit[] {
+	return edits.filter((textEdit) => textEdit.newEol === undefined || textEdit.newText !== '');
+}
+
+/**
+ * Lists the text edits of a workspace edit like workspaceEditChanges, with characters in the given encoding.
+ */
+export async function encodedWorkspaceEditChanges(
+	edit: vscode.WorkspaceEdit,
+	encoding: OffsetEncoding | undefined
+): Promise<FileChange[]> {
+	if (encoding === undefined || encoding === 'utf16') {
+		return workspaceEditChanges(edit);
+	}
+	return Promise.all(
+		edit.entries().map(async ([uri, edits]) => {
+			const document = await vscode.workspace.openTextDocument(uri);
+			return {
+				path: displayPath(uri),
+				edits: reportedEdits(edits).map((textEdit) => ({
+					range: toEncodedLineRange(document, textEdit.range, encoding),
+					newText: textEdit.newText,
+				})),
+			};
+		})
+	);
+}
+
+// Converts text to the given line endings
+export function withLineEndings(text: string, eol: vscode.EndOfLine): string {
+	const normalized = text.replace(/\r\n/g, '\n');
+	return eol === vscode.EndOfLine.CRLF ? normalized.replace(/\n/g, '\r\n') : normalized;
+}
+
+// Resolves an eol mode to line endings, falling back to the given ones for auto
+export function resolveEol(mode: EolMode | undefined, fallback: vscode.EndOfLine): vscode.EndOfLine {
+	if (mode === 'lf') {
+		return vscode.EndOfLine.LF;
+	}
+	if (mode === 'crlf') {
+		return vscode.EndOfLine.CRLF;
+	}
+	return fallback;
+}
+
+// Line endings of a new file per the files.eol setting, whose auto means the platform's
+export function newFileEol(uri: vscode.Uri): vscode.EndOfLine {
+	const setting = vscode.workspace.getConfiguration('files', uri).get<string>('eol');
+	const crlf = setting === 'auto' || setting === undefined ? process.platform === 'win32' : setting === '\r\n';
+	return crlf ? vscode.EndOfLine.CRLF : vscode.EndOfLine.LF;
+}
+
+/**
+ * Converts text edits to a document's line endings, or to the ones eol asks for, in which case the
+ * returned edits also change the rest of the document's line endings.
+ */
+export function textEditsWithEol(
+	document: vscode.TextDocument,
+	edits: Array<{ range: vscode.Range; newText: string }>,
+	eol: EolMode | undefined
+): vscode.TextEdit[] {
+	const target = resolveEol(eol, document.eol);
+	const textEdits = edits.map(({ range, newText }) =>
+		vscode.TextEdit.replace(range, withLineEndings(newText, target))
+	);
+	if (target !== document.eol) {
+		textEdits.push(vscode.TextEdit.setEndOfLine(target));
+	}
+	return textEdits;
+}
+
+export interface ApplyEditRequest {
+	path: string;
+	edits: Array<{ range: LineRange; newText: string }>;
+	eol?: EolMode;
+	offsetEncoding?: OffsetEncoding;
+}
+
+/**
+ * Applies text edits to a file as one workspace edit, so either all of them apply or none do. The
+ * document is left unsaved, a BOM it has is kept when it is saved.
+ */
+export async function applyEdit(
+	{ path: filePath, edits, eol, offsetEncoding }: ApplyEditRequest,
+	dryRun: boolean
+): Promise<ToolResult> {
+	const document = await vscode.workspace.openTextDocument(vscode.Uri.file(filePath));
+	const ranges: Array<{ range: vscode.Range; newText: string }> = [];
+	for (const [index, { range, newText }] of edits.entries()) {
+		const target = fromEncodedLineRange(document, range, offsetEncoding);
+		if (!document.validateRange(target).isEqual(target)) {
+			return {
+				success: false,
+				error: `Edit ${index}: range is outside the document, which has ${document.lineCount} lines`,
+			};
+		}
+		ranges.push({ range: target, newText });
+	}
+	const edit = new vscode.WorkspaceEdit();
+	edit.set(document.uri, textEditsWithEol(document, ranges, eol));
+
+	if (dryRun) {
+		const changes = await encodedWorkspaceEditChanges(edit, offsetEncoding);
+		return { success: true, data: { dryRun: true, changes } };
+	}
+	if (!(await vscode.workspace.applyEdit(edit))) {
+		return {
+			success: false,
+			error: 'VS Code refused to apply the edit, e.g. because the file is read-only',
+			data: { applied: false, version: document.version },
+		};
+	}
+	return { success: true, data: { applied: true, version: document.version } };
+}
+
+export interface MoveFileRequest {
+	from: string;
+	to: string;
+	overwrite?: boolean;
+}
+
+// How long to wait after a move for language extensions to rewrite imports, they do so once it is done
+const importUpdateGracePeriod = 1500;
+
+/**
+ * Moves a file or folder with a workspace edit, so language extensions update the imports referring to it.
+ */
+export async function moveFile({ from, to, overwrite }: MoveFileRequest, dryRun: boolean): Promise<ToolResult> {
+	if (!fs.existsSync(from)) {
+		return { success: false, error: `Not found: ${from}` };
+	}
+	if (!overwrite && fs.existsSync(to)) {
+		return { success: false, error: `Destination exists, pass overwrite: true to replace it: ${to}` };
+	}
+	if (dryRun) {
+		// Import updates are computed by the language extensions once the file moved, they can't be previewed
+		return { success: true, data: { dryRun: true, changes: [{ path: from, operation: 'rename', to }] } };
+	}
+
+	const edit = new vscode.WorkspaceEdit();
+	edit.renameFile(vscode.Uri.file(from), vscode.Uri.file(to), { overwrite: overwrite === true });
+	const updated = new Set<string>();
+	const listener = vscode.workspace.onDidChangeTextDocument((event) => {
+		if (event.contentChanges.length > 0 && event.document.uri.scheme === 'file') {
+			updated.add(event.document.uri.fsPath);
+		}
+	});
+	try {
+		await fs.promises.mkdir(path.dirname(to), { recursive: true });
+		if (!(await vscode.workspace.applyEdit(edit))) {
+			return { success: false, error: `VS Code refused to move ${from} to ${to}` };
+		}
+		await new Promise((resolve) => setTimeout(resolve, importUpdateGracePeriod));
+	} finally {
+		listener.dispose();
+	}
+	return { success: true, data: { moved: true, from, to, updatedFiles: [...updated].sort() } };
+}
@@ -0,0 +1,462 @@
+import { execFile } from 'child_process';
+import * as fs from 'fs';
+import * as path from 'path';
+import { promisify } from 'util';
+import * as vscode from 'vscode';
+import { logger } from '../logger';
+import type { GitAPI, GitExtension, Repository } from './open-tool';
+import type { ToolResult } from './types';
+
+const execFilePromise = promisify(execFile);
+
+// Returns the git extension's API once it has discovered the repositories
+export async function gitAPI(): Promise<GitAPI> {
+	const gitExtension = vscode.extensions.getExtension<GitExtension>('vscode.git');
+	if (!gitExtension) {
+		throw new Error('Git extension not available');
+	}
+	if (!gitExtension.isActive) {
+		await gitExtension.activate();
+	}
+
+	const git = gitExtension.exports.getAPI(1);
+	if (git.state !== 'initialized') {
+		await new Promise<void>((resolve) => {
+			const disposable = git.onDidChangeState((state) => {
+				if (state === 'initialized') {
+					disposable.dispose();
+					resolve();
+				}
+			});
+			// Timeout after 5 seconds
+			setTimeout(() => {
+				disposable.dispose();
+				resolve();
+			}, 5000);
+		});
+	}
+	return git;
+}
+
+// Returns the innermost open repository containing a file
+export async function repositoryFor(filePath: string): Promise<Repository> {
+	const git = await gitAPI();
+	const containing = git.repositories
+		.filter((repo) => {
+			const relative = path.relative(repo.rootUri.fsPath, filePath);
+			return !relative.startsWith('..') && !path.isAbsolute(relative);
+		})
+		.sort((a, b) => b.rootUri.fsPath.length - a.rootUri.fsPath.length);
+	if (containing.length === 0) {
+		throw new Error(`File not in any open git repository: ${filePath}`);
+	}
+	return containing[0];
+}
+
+// Runs git in a repository and returns its standard output
+export async function runGit(repo: string, args: string[]): Promise<string> {
+	logger.debug('GitTools', `git ${args.join(' ')} (in ${repo})`);
+	const { stdout } = await execFilePromise('git', args, { cwd: repo, maxBuffer: 16 * 1024 * 1024 });
+	return stdout;
+}
+
+// Returns a file's path relative to its repository, with forward slashes as git expects
+function repoRelative(repo: string, filePath: string): string {
+	return path.relative(repo, filePath).split(path.sep).join('/');
+}
+
+// Field and record separators for git log formats, they can't appear in commit metadata
+const fieldSeparator = '\x1f';
+const recordSeparator = '\x1e';
+
+export interface OpenScmRequest {
+	path?: string;
+}
+
+/**
+ * Focuses the Source Control view, optionally showing a changed file's change so the view selects it.
+ */
+export async function openScm({ path: filePath }: OpenScmRequest): Promise<ToolResult> {
+	if (!filePath) {
+		await vscode.commands.executeCommand('workbench.view.scm');
+		return { success: true, data: { focused: true } };
+	}
+
+	const repo = await repositoryFor(filePath);
+	const groups: Array<[string, Array<{ uri: vscode.Uri }> | undefined]> = [
+		['index', repo.state.indexChanges],
+		['workingTree', repo.state.workingTreeChanges],
+		['untracked', repo.state.untrackedChanges],
+		['merge', repo.state.mergeChanges],
+	];
+	const group = groups.find(([, changes]) => changes?.some((change) => change.uri.fsPath === filePath))?.[0];
+	if (!group) {
+		return { success: false, error: `File has no pending changes: ${filePath}` };
+	}
+
+	// The view follows the active editor (scm.autoReveal), so opening the change selects it
+	await vscode.commands.executeCommand('git.openChange', vscode.Uri.file(filePath));
+	await vscode.commands.executeCommand('workbench.view.scm');
+	return { success: true, data: { focused: true, path: filePath, group } };
+}
+
+export interface FileHistoryDiffRequest {
+	path: string;
+	repo?: string;
+	from?: string;
+	to?: string;
+}
+
+/**
+ * Lists the commits that touched a file, newest first, and opens the diff between two of them.
+ */
+export async function fileHistoryDiff({ path: filePath, repo, from, to }: FileHistoryDiffRequest): Promise<ToolResult> {
+	const root = repo ?? (await repositoryFor(filePath)).rootUri.fsPath;
+	const log = await runGit(root, [
+		'log',
+		'--follow',
+		`--format=%H${fieldSeparator}%an${fieldSeparator}%aI${fieldSeparator}%s${recordSeparator}`,
+		'--',
+		repoRelative(root, filePath),
+	]);
+	const commits = log
+		.split(recordSeparator)
+		.map((record) => record.trim())
+		.filter((record) => record)
+		.map((record) => {
+			const [hash, author, date, summary] = record.split(fieldSeparator);
+			return { hash, author, date, summary };
+		});
+
+	if (from && to) {
+		const findCommit = (ref: string) => commits.find((commit) => commit.hash.startsWith(ref));
+		const fromCommit = findCommit(from);
+		const toCommit = findCommit(to);
+		if (!fromCommit || !toCommit) {
+			return { success: false, error: `Not a commit that touched ${filePath}: ${fromCommit ? to : from}` };
+		}
+		const git = await gitAPI();
+		const uri = vscode.Uri.file(filePath);
+		await vscode.commands.executeCommand(
+			'vscode.diff',
+			git.toGitUri(uri, fromCommit.hash),
+			git.toGitUri(uri, toCommit.hash),
+			`${path.basename(filePath)} (${fromCommit.hash.slice(0, 7)} ↔ ${toCommit.hash.slice(0, 7)})`,
+			{ preview: false }
+		);
+	}
+
+	return { success: true, data: { repo: root, commits } };
+}
+
+// Returns the given repository, or by default the one of the first workspace folder
+async function repositoryRoot(repo: string | undefined): Promise<string> {
+	if (repo) {
+		return repo;
+	}
+	const folder = vscode.workspace.workspaceFolders?.find((candidate) => candidate.uri.scheme === 'file');
+	if (!folder) {
+		throw new Error('No folder open in VS Code, pass a repo');
+	}
+	return (await repositoryFor(folder.uri.fsPath)).rootUri.fsPath;
+}
+
+export interface GitStashListRequest {
+	repo?: string;
+}
+
+// Lists the stash entries of a repository, newest first
+async function listStashes(root: string) {
+	const output = await runGit(root, [
+		'stash',
+		'list',
+		`--format=%gd${fieldSeparator}%gs${fieldSeparator}%aI${recordSeparator}`,
+	]);
+	return output
+		.split(recordSeparator)
+		.map((record) => record.trim())
+		.filter((record) => record)
+		.map((record, index) => {
+			const [ref, message, date] = record.split(fieldSeparator);
+			// Stash subjects are "WIP on <branch>: ..." or "On <branch>: <message>"
+			const branch = /^(?:WIP on|On) ([^:]+):/.exec(message)?.[1] ?? null;
+			return { index, ref, message, branch, date };
+		});
+}
+
+/**
+ * Lists the stash entries of a repository, newest first.
+ */
+export async function gitStashList({ repo }: GitStashListRequest): Promise<ToolResult> {
+	const root = await repositoryRoot(repo);
+	return { success: true, data: { repo: root, stashes: await listStashes(root) } };
+}
+
+export interface GitStashRequest {
+	action: 'apply' | 'pop' | 'drop' | 'push';
+	stash?: string;
+	message?: string;
+	staged?: boolean;
+	includeUntracked?: boolean;
+	repo?: string;
+}
+
+// Returns the files with unresolved merge conflicts
+async function conflictedFiles(root: string): Promise<string[]> {
+	const output = await runGit(root, ['diff', '--name-only', '--diff-filter=U', '-z']);
+	return output
+		.split('\0')
+		.filter((file) => file)
+		.map((file) => path.join(root, file));
+}
+
+/**
+ * Pushes, applies, pops, or drops a stash and reports the resulting stash list and any conflicts.
+ */
+export async function gitStash({
+	action,
+	stash = 'stash@{0}',
+	message,
+	staged,
+	includeUntracked,
+	repo,
+}: GitStashRequest): Promise<ToolResult> {
+	const root = await repositoryRoot(repo);
+	let args: string[];
+	if (action === 'push') {
+		args = ['stash', 'push'];
+		if (staged) {
+			args.push('--staged');
+		}
+		if (includeUntracked) {
+			args.push('--include-untracked');
+		}
+		if (message) {
+			args.push('--message', message);
+		}
+	} else {
+		args = ['stash', action, stash];
+	}
+
+	let conflicts: string[] = [];
+	try {
+		await runGit(root, args);
+	} catch (error) {
+		// apply and pop exit with an error when the stash applied with conflicts, a pop then keeps the stash
+		conflicts = action === 'apply' || action === 'pop' ? await conflictedFiles(root) : [];
+		if (conflicts.length === 0) {
+			throw error;
+		}
+	}
+	return { success: true, data: { repo: root, action, stashes: await listStashes(root), conflicts } };
+}
+
+export interface GetGitStatusRequest {
+	repo?: string;
+}
+
+interface StatusEntry {
+	status: string;
+	path: string;
+	from?: string;
+}
+
+/**
+ * Lists a repository's staged, unstaged, and untracked files, with the current branch.
+ */
+export async function getGitStatus({ repo }: GetGitStatusRequest): Promise<ToolResult> {
+	let root: string;
+	try {
+		root = await repositoryRoot(repo);
+	} catch (error) {
+		return { success: false, error: `not a git repository: ${error instanceof Error ? error.message : error}` };
+	}
+
+	// -z keeps paths unquoted, a rename's entry is followed by its original path
+	const tokens = (await runGit(root, ['status', '--porcelain=v1', '-z', '--untracked-files=all'])).split('\0');
+	const staged: StatusEntry[] = [];
+	const unstaged: StatusEntry[] = [];
+	const untracked: StatusEntry[] = [];
+	for (let i = 0; i < tokens.length; i++) {
+		const entry = tokens[i];
+		if (entry.length < 4) {
+			continue;
+		}
+		const [x, y] = entry;
+		const filePath = path.join(root, entry.slice(3));
+		const from = x === 'R' || x === 'C' || y === 'R' || y === 'C' ? path.join(root, tokens[++i]) : undefined;
+		if (x === '?') {
+			untracked.push({ status: '?', path: filePath });
+		} else if (x === 'U' || y === 'U' || (x === 'A' && y === 'A') || (x === 'D' && y === 'D')) {
+			// Unmerged files need resolving in the working tree
+			unstaged.push({ status: 'U', path: filePath });
+		} else {
+			if (x !== ' ') {
+				staged.push({ status: x, path: filePath, ...(from && (x === 'R' || x === 'C') ? { from } : {}) });
+			}
+			if (y !== ' ') {
+				unstaged.push({ status: y, path: filePath, ...(from && (y === 'R' || y === 'C') ? { from } : {}) });
+			}
+		}
+	}
+
+	// Empty for a detached HEAD
+	const branch = (await runGit(root, ['branch', '--show-current'])).trim() || null;
+	return { success: true, data: { repo: root, branch, staged, unstaged, untracked } };
+}
+
+/**
+ * References the active editor's selection by file and line:column, and by commit for files tracked by git.
+ */
+export async function getLocationRef(): Promise<ToolResult> {
+	const editor = vscode.window.activeTextEditor;
+	if (!editor) {
+		return { success: true, data: { file: null, git: null } };
+	}
+
+	const uri = editor.document.uri;
+	const { start, end } = editor.selection;
+	const filePath = uri.scheme === 'file' ? uri.fsPath : uri.toString();
+	const startLine = start.line + 1;
+	const endLine = end.line + 1;
+	const file = {
+		path: filePath,
+		startLine,
+		startColumn: start.character + 1,
+		endLine,
+		endColumn: end.character + 1,
+		ref: `${filePath}:${startLine}:${start.character + 1}-${endLine}:${end.character + 1}`,
+	};
+	if (uri.scheme !== 'file') {
+		return { success: true, data: { file, git: null } };
+	}
+
+	let git: { repo: string; commit: string; path: string; ref: string } | null = null;
+	try {
+		const repo = (await repositoryFor(filePath)).rootUri.fsPath;
+		const relative = repoRelative(repo, filePath);
+		// Fails for untracked files
+		await runGit(repo, ['ls-files', '--error-unmatch', '--', relative]);
+		const commit = (await runGit(repo, ['rev-parse', 'HEAD'])).trim();
+		const lines = startLine === endLine ? `L${startLine}` : `L${startLine}-L${endLine}`;
+		git = { repo, commit, path: relative, ref: `${commit.slice(0, 7)}:${relative}#${lines}` };
+	} catch (error) {
+		logger.debug('GitTools', `No git reference for ${filePath}: ${error}`);
+	}
+	return { success: true, data: { file, git } };
+}
+
+export interface GitLogRequest {
+	repo?: string;
+	path?: string;
+	max?: number;
+}
+
+/**
+ * Lists the most recent commits of a repository, optionally only those touching a path.
+ */
+export async function gitLog({ repo, path: filePath, max = 50 }: GitLogRequest): Promise<ToolResult> {
+	const root = repo ?? (filePath ? (await repositoryFor(filePath)).rootUri.fsPath : await repositoryRoot(undefined));
+	const format = ['%H', '%P', '%an <%ae>', '%aI', '%B'].join(fieldSeparator);
+	const args = ['log', `--max-count=${max}`, `--format=${format}${recordSeparator}`];
+	if (filePath) {
+		args.push('--', repoRelative(root, filePath));
+	}
+	const commits = (await runGit(root, args))
+		.split(recordSeparator)
+		.map((record) => record.trim())
+		.filter((record) => record)
+		.map((record) => {
+			const [hash, parents, author, date, message] = record.split(fieldSeparator);
+			return { hash, parents: parents ? parents.split(' ') : [], author, date, message: message.trim() };
+		});
+	return { success: true, data: { repo: root, commits } };
+}
+
+export interface OpenCommitRequest {
+	commit: string;
+	repo?: string;
+}
+
+/**
+ * Opens a multi-file diff of everything a commit changed, against its first parent.
+ */
+export async function openCommit({ commit, repo }: OpenCommitRequest): Promise<ToolResult> {
+	const root = await repositoryRoot(repo);
+	const hash = (await runGit(root, ['rev-parse', '--verify', `${commit}^{commit}`])).trim();
+	const hasParent = (await runGit(root, ['rev-list', '--parents', '--max-count=1', hash])).trim().includes(' ');
+	// A root commit is diffed against the empty tree, a merge commit against its first parent
+	const output = hasParent
+		? await runGit(root, ['diff', '--name-only', '--no-renames', '-z', `${hash}^`, hash])
+		: await runGit(root, ['diff-tree', '--root', '-r', '--no-commit-id', '--name-only', '-z', hash]);
+	const files = output.split('\0').filter((file) => file);
+	if (files.length === 0) {
+		return { success: false, error: `Commit ${hash.slice(0, 7)} changed no files` };
+	}
+
+	// A file missing on one side, like the parent side of an added file, shows as empty
+	const git = await gitAPI();
+	const resources = files.map((file) => {
+		const fileUri = vscode.Uri.file(path.join(root, file));
+		return [fileUri, git.toGitUri(fileUri, `${hash}^`), git.toGitUri(fileUri, hash)];
+	});
+	await vscode.commands.executeCommand('vscode.changes', `${hash.slice(0, 7)} (${path.basename(root)})`, resources);
+	return { success: true, data: { repo: root, commit: hash, files: files.length } };
+}
+
+export interface GitRestoreRequest {
+	path: string;
+	ref?: string;
+	repo?: string;
+}
+
+/**
+ * Restores a file in the working tree to its content at a revision, discarding its local changes.
+ */
+export async function gitRestore({ path: filePath, ref = 'HEAD', repo }: GitRestoreRequest): Promise<ToolResult> {
+	const root = repo ?? (await repositoryFor(filePath)).rootUri.fsPath;
+	const relative = repoRelative(root, filePath);
+	// Reported so the discarded content can still be recovered with git cat-file
+	const previousHash = fs.existsSync(filePath)
+		? (await runGit(root, ['hash-object', '-w', '--', relative])).trim()
+		: null;
+	await runGit(root, ['restore', `--source=${ref}`, '--worktree', '--', relative]);
+	return { success: true, data: { repo: root, path: filePath, ref, previousHash } };
+}
+
+export interface GitBlameRequest {
+	path: string;
+	startLine?: number;
+	endLine?: number;
+}
+
+/**
+ * Reports the commit, author and date that last changed each line of a file on disk.
+ */
+export async function gitBlame({ path: filePath, startLine, endLine }: GitBlameRequest): Promise<ToolResult> {
+	const root = (await repositoryFor(filePath)).rootUri.fsPath;
+	const args = ['blame', '--line-porcelain'];
+	if (startLine !== undefined || endLine !== undefined) {
+		args.push('-L', `${startLine ?? 1},${endLine ?? ''}`);
+	}
+	args.push('--', repoRelative(root, filePath));
+
+	// Each line comes as a "<commit> <original line> <final line>" header, its commit's fields, then its content
+	const lines: Array<{ line: number; commit: string; author: string; date: string; summary: string }> = [];
+	let current: (typeof lines)[number] | undefined;
+	for (const entry of (await runGit(root, args)).split('\n')) {
+		const header = /^([0-9a-f]{40,64}) \d+ (\d+)/.exec(entry);
+		if (header) {
+			current = { line: Number(header[2]), commit: header[1], author: '', date: '', summary: '' };
+			lines.push(current);
+		} else if (current && entry.startsWith('author ')) {
+			current.author = entry.slice('author '.length);
+		} else if (current && entry.startsWith('author-time ')) {
+			const time = Number(entry.slice('author-time '.length));
+			current.date = new Date(time * 1000).toISOString().replace('.000Z', 'Z');
+		} else if (current && entry.startsWith('summary ')) {
+			current.summary = entry.slice('summary '.length);
+		}
+	}
+	return { success: true, data: { repo: root, lines } };
+}
@@ -0,0 +1,479 @@
+import * as vscode from 'vscode';
+import { displayPath, encodedWorkspaceEditChanges, workspaceEditChanges } from './edits';
+import { fromEncodedPosition, toEncodedLineRangeIn } from './positions';
+import type { OffsetEncoding, ToolResult } from './types';
+
+// A position in a file, with a 1-based line and 0-based character in offsetEncoding (default utf16)
+export interface PositionRequest {
+	path: string;
+	line: number;
+	character: number;
+	offsetEncoding?: OffsetEncoding;
+}
+
+// Opens the document of a position request and converts the position to VS Code's
+async function resolvePosition(
+	request: PositionRequest
+): Promise<{ document: vscode.TextDocument; position: vscode.Position }> {
+	const document = await vscode.workspace.openTextDocument(vscode.Uri.file(request.path));
+	const requested = fromEncodedPosition(document, request.line, request.character, request.offsetEncoding);
+	const position = document.validatePosition(requested);
+	return { document, position };
+}
+
+/**
+ * Reports the symbols enclosing a position, outermost first, like the editor's breadcrumbs.
+ */
+export async function getBreadcrumbs(request: PositionRequest): Promise<ToolResult> {
+	const { document, position } = await resolvePosition(request);
+	const symbols =
+		(await vscode.commands.executeCommand<Array<vscode.DocumentSymbol | vscode.SymbolInformation>>(
+			'vscode.executeDocumentSymbolProvider',
+			document.uri
+		)) ?? [];
+
+	const trail: Array<{ name: string; kind: string }> = [];
+	if (symbols.length > 0 && 'children' in symbols[0]) {
+		// Hierarchical symbols, descend into the child containing the position
+		let level = symbols as vscode.DocumentSymbol[];
+		for (;;) {
+			const enclosing = level.find((symbol) => symbol.range.contains(position));
+			if (!enclosing) {
+				break;
+			}
+			trail.push({ name: enclosing.name, kind: vscode.SymbolKind[enclosing.kind] });
+			level = enclosing.children;
+		}
+	} else {
+		// Flat symbols, the enclosing ones nest by range size
+		const enclosing = (symbols as vscode.SymbolInformation[])
+			.filter((symbol) => symbol.location.range.contains(position))
+			.sort((a, b) => (a.location.range.contains(b.location.range) ? -1 : 1));
+		for (const symbol of enclosing) {
+			trail.push({ name: symbol.name, kind: vscode.SymbolKind[symbol.kind] });
+		}
+	}
+
+	return { success: true, data: { trail, text: trail.map((crumb) => crumb.name).join(' > ') } };
+}
+
+// Normalizes the Location | LocationLink results of definition-like providers
+function toLocations(results: Array<vscode.Location | vscode.LocationLink> | undefined): vscode.Location[] {
+	return (results ?? []).map((result) =>
+		'targetUri' in result
+			? new vscode.Location(result.targetUri, result.targetSelectionRange ?? result.targetRange)
+			: result
+	);
+}
+
+// Asks the definition providers for the definitions of the symbol at a position
+async function definitionsAt(uri: vscode.Uri, position: vscode.Position): Promise<vscode.Location[]> {
+	return toLocations(
+		await vscode.commands.executeCommand<Array<vscode.Location | vscode.LocationLink>>(
+			'vscode.executeDefinitionProvider',
+			uri,
+			position
+		)
+	);
+}
+
+export interface ResolveImportRequest {
+	path: string;
+	importSpec: string;
+}
+
+/**
+ * Resolves an import specifier to the files it refers to, via the definition of the specifier in the file.
+ */
+export async function resolveImport({ path, importSpec }: ResolveImportRequest): Promise<ToolResult> {
+	const document = await vscode.workspace.openTextDocument(vscode.Uri.file(path));
+	const text = document.getText();
+
+	// The specifier as a string literal, in any quote style
+	const index = ['"', "'", '`']
+		.map((quote) => text.indexOf(`${quote}${importSpec}${quote}`))
+		.filter((found) => found >= 0)
+		.sort((a, b) => a - b)[0];
+	if (index === undefined) {
+		return { success: false, error: `Import not found in ${path}: ${importSpec}` };
+	}
+
+	// Inside the quotes, so the provider sees the module name rather than the quote
+	const position = document.positionAt(index + 1);
+	const locations = await definitionsAt(document.uri, position);
+	const resolved = [...new Set(locations.map((location) => location.uri.fsPath))];
+	return { success: true, data: { resolved, ambiguous: resolved.length > 1 } };
+}
+
+// Converts locations to the shape reported to the MCP server
+function toLocationInfos(locations: vscode.Location[], encoding: OffsetEncoding | undefined) {
+	return Promise.all(
+		locations.map(async (location) => ({
+			path: displayPath(location.uri),
+			range: await toEncodedLineRangeIn(location.uri, location.range, encoding),
+		}))
+	);
+}
+
+// Shows a document with the cursor at a position, so editor actions like the peek views run there
+async function showAtPosition(document: vscode.TextDocument, position: vscode.Position): Promise<void> {
+	await vscode.window.showTextDocument(document, {
+		selection: new vscode.Range(position, position),
+		preview: false,
+	});
+}
+
+export interface GoToDefinitionRequest extends PositionRequest {
+	open?: boolean;
+	peek?: boolean;
+}
+
+/**
+ * Finds the definitions of the symbol at a position, optionally showing the first one in an editor
+ * or all of them in a peek view at the position.
+ */
+export async function goToDefinition({ open, peek, ...request }: GoToDefinitionRequest): Promise<ToolResult> {
+	const { document, position } = await resolvePosition(request);
+	const definitions = await definitionsAt(document.uri, position);
+
+	if (peek) {
+		const peeked = definitions.length > 0;
+		if (peeked) {
+			await showAtPosition(document, position);
+			await vscode.commands.executeCommand('editor.action.peekDefinition');
+		}
+		const infos = await toLocationInfos(definitions, request.offsetEncoding);
+		return { success: true, data: { peeked, definitions: infos } };
+	}
+	if (open && definitions.length > 0) {
+		await vscode.window.showTextDocument(definitions[0].uri, { selection: definitions[0].range, preview: false });
+	}
+	return { success: true, data: await toLocationInfos(definitions, request.offsetEncoding) };
+}
+
+// Converts a position in a file to the shape reported to the MCP server
+async function toPositionInfo(uri: vscode.Uri, position: vscode.Position, encoding: OffsetEncoding | undefined) {
+	const { startCharacter } = await toEncodedLineRangeIn(uri, new vscode.Range(position, position), encoding);
+	return { path: displayPath(uri), line: position.line + 1, character: startCharacter };
+}
+
+/**
+ * Opens the first definition of the symbol at a position in an editor beside the current one.
+ */
+export async function openDefinitionBeside(request: PositionRequest): Promise<ToolResult> {
+	const { document, position } = await resolvePosition(request);
+	const [first, ...others] = await definitionsAt(document.uri, position);
+	if (!first) {
+		throw new Error(`No definition found at ${request.path}:${request.line}:${request.character}`);
+	}
+
+	await vscode.window.showTextDocument(first.uri, {
+		viewColumn: vscode.ViewColumn.Beside,
+		selection: first.range,
+		preview: false,
+	});
+	return {
+		success: true,
+		data: {
+			source: await toPositionInfo(document.uri, position, request.offsetEncoding),
+			opened: await toPositionInfo(first.uri, first.range.start, request.offsetEncoding),
+			others: await Promise.all(
+				others.map((other) => toPositionInfo(other.uri, other.range.start, request.offsetEncoding))
+			),
+		},
+	};
+}
+
+export interface FindReferencesRequest extends PositionRequest {
+	includeDeclaration?: boolean;
+	maxResults?: number;
+	peek?: boolean;
+}
+
+/**
+ * Finds the references to the symbol at a position across the workspace, optionally showing them in a
+ * peek view at the position.
+ */
+export async function findReferences({
+	includeDeclaration = true,
+	maxResults,
+	peek,
+	...request
+}: FindReferencesRequest): Promise<ToolResult> {
+	const { document, position } = await resolvePosition(request);
+	let references =
+		(await vscode.commands.executeCommand<vscode.Location[]>(
+			'vscode.executeReferenceProvider',
+			document.uri,
+			position
+		)) ?? [];
+
+	// The reference provider command always includes the declaration, drop the definitions' locations
+	if (!includeDeclaration) {
+		const definitions = await definitionsAt(document.uri, position);
+		references = references.filter(
+			(reference) =>
+				!definitions.some(
+					(definition) =>
+						definition.uri.toString() === reference.uri.toString() &&
+						definition.range.intersection(reference.range) !== undefined
+				)
+		);
+	}
+
+	const total = references.length;
+	const data = {
+		references: await toLocationInfos(references.slice(0, maxResults ?? total), request.offsetEncoding),
+		total,
+	};
+	if (peek) {
+		// Peek the filtered references rather than re-running the provider, so the view matches the result
+		const peeked = total > 0;
+		if (peeked) {
+			await showAtPosition(document, position);
+			await vscode.commands.executeCommand('editor.action.showReferences', document.uri, position, references);
+		}
+		return { success: true, data: { ...data, peeked } };
+	}
+	return { success: true, data };
+}
+
+export interface RenameRequest extends PositionRequest {
+	newName: string;
+}
+
+/**
+ * Renames the symbol at a position with the rename provider, applying every file's edits in one
+ * workspace edit. Changed files are left unsaved.
+ */
+export async function renameSymbol({ newName, ...request }: RenameRequest, dryRun: boolean): Promise<ToolResult> {
+	const { document, position } = await resolvePosition(request);
+	// Rejected renames, e.g. of a keyword, throw with the provider's message
+	const edit = await vscode.commands.executeCommand<vscode.WorkspaceEdit | undefined>(
+		'vscode.executeDocumentRenameProvider',
+		document.uri,
+		position,
+		newName
+	);
+	if (!edit || edit.size === 0) {
+		const where = `${request.path}:${request.line}:${request.character}`;
+		return { success: false, error: `No renameable symbol at ${where}` };
+	}
+
+	const changes = await encodedWorkspaceEditChanges(edit, request.offsetEncoding);
+	if (dryRun) {
+		return { success: true, data: { dryRun: true, changes } };
+	}
+
+	const summary = changes.map(({ path, edits }) => ({ path, ranges: edits.map(({ range }) => range) }));
+	if (!(await vscode.workspace.applyEdit(edit))) {
+		// Workspace edits are applied as a whole, so nothing was changed
+		return {
+			success: false,
+			error: 'VS Code refused to apply the rename, e.g. because a file changed meanwhile',
+			data: { applied: false, changed: [], unchanged: summary.map(({ path }) => path) },
+		};
+	}
+	return { success: true, data: { applied: true, changes: summary } };
+}
+
+// Applies a code action's edit and command. Returns the result to report instead for dry runs, which
+// list the edit's changes with characters in the given encoding, and failures.
+async function applyCodeAction(
+	action: vscode.CodeAction,
+	dryRun: boolean,
+	encoding?: OffsetEncoding
+): Promise<ToolResult | undefined> {
+	if (dryRun) {
+		if (action.command) {
+			// What a command changes can't be known before running it
+			return { success: false, error: `Code action "${action.title}" runs a command, it can't be previewed` };
+		}
+		const changes = action.edit ? await encodedWorkspaceEditChanges(action.edit, encoding) : [];
+		return { success: true, data: { dryRun: true, changes } };
+	}
+	if (action.edit && !(await vscode.workspace.applyEdit(action.edit))) {
+		return { success: false, error: `VS Code refused to apply the edit of code action "${action.title}"` };
+	}
+	if (action.command) {
+		await vscode.commands.executeCommand(action.command.command, ...(action.command.arguments ?? []));
+	}
+	return undefined;
+}
+
+export interface OrganizeImportsRequest {
+	path: string;
+}
+
+/**
+ * Runs the source.organizeImports code action of a file, leaving it unsaved.
+ */
+export async function organizeImports({ path }: OrganizeImportsRequest, dryRun: boolean): Promise<ToolResult> {
+	const document = await vscode.workspace.openTextDocument(vscode.Uri.file(path));
+	await vscode.window.showTextDocument(document, { preview: false });
+
+	const fullRange = new vscode.Range(0, 0, document.lineCount, 0);
+	const actions =
+		(await vscode.commands.executeCommand<vscode.CodeAction[]>(
+			'vscode.executeCodeActionProvider',
+			document.uri,
+			fullRange,
+			vscode.CodeActionKind.SourceOrganizeImports.value
+		)) ?? [];
+	const action = actions.find((candidate) =>
+		candidate.kind ? vscode.CodeActionKind.SourceOrganizeImports.contains(candidate.kind) : false
+	);
+	if (!action) {
+		return { success: false, error: `No organize imports provider is available for ${document.languageId}` };
+	}
+
+	const versionBefore = document.version;
+	const outcome = await applyCodeAction(action, dryRun);
+	if (outcome) {
+		return outcome;
+	}
+	return { success: true, data: { applied: document.version !== versionBefore, version: document.version } };
+}
+
+export interface FormatDocumentRequest {
+	path: string;
+	startLine?: number;
+	endLine?: number;
+}
+
+/**
+ * Formats a document, or a range of its lines, with the formatter configured for its language.
+ */
+export async function formatDocument(
+	{ path, startLine, endLine }: FormatDocumentRequest,
+	dryRun: boolean
+): Promise<ToolResult> {
+	const document = await vscode.workspace.openTextDocument(vscode.Uri.file(path));
+	// Format with the indentation the editor uses, which may have been detected from the content
+	const editor = await vscode.window.showTextDocument(document, { preview: false });
+	const options: vscode.FormattingOptions = {
+		tabSize: Number(editor.options.tabSize),
+		insertSpaces: editor.options.insertSpaces === true,
+	};
+
+	let edits: vscode.TextEdit[] | undefined;
+	if (startLine === undefined) {
+		edits = await vscode.commands.executeCommand<vscode.TextEdit[]>(
+			'vscode.executeFormatDocumentProvider',
+			document.uri,
+			options
+		);
+	} else {
+		const lastLine = Math.min(endLine ?? startLine, document.lineCount) - 1;
+		const range = new vscode.Range(startLine - 1, 0, lastLine, document.lineAt(lastLine).range.end.character);
+		edits = await vscode.commands.executeCommand<vscode.TextEdit[]>(
+			'vscode.executeFormatRangeProvider',
+			document.uri,
+			range,
+			options
+		);
+	}
+	if (!edits) {
+		const kind = startLine === undefined ? 'formatter' : 'range formatter';
+		return {
+			success: true,
+			data: {
+				formatted: false,
+				changed: false,
+				message: `No ${kind} available for language '${document.languageId}'`,
+			},
+		};
+	}
+
+	const edit = new vscode.WorkspaceEdit();
+	edit.set(document.uri, edits);
+	if (dryRun) {
+		return { success: true, data: { dryRun: true, changes: edits.length > 0 ? workspaceEditChanges(edit) : [] } };
+	}
+	if (edits.length > 0 && !(await vscode.workspace.applyEdit(edit))) {
+		return { success: false, error: `VS Code refused to apply the formatting edits to ${path}` };
+	}
+	return { success: true, data: { formatted: true, changed: edits.length > 0, version: document.version } };
+}
+
+export interface CodeActionsRequest extends PositionRequest {
+	apply?: string;
+}
+
+// How many code actions get their lazily computed edits resolved before one is applied
+const maxResolvedCodeActions = 100;
+
+/**
+ * Lists the code actions available at a position, or applies the one with the given title.
+ */
+export async function codeActions({ apply, ...request }: CodeActionsRequest, dryRun: boolean): Promise<ToolResult> {
+	const { document, position } = await resolvePosition(request);
+	const results =
+		(await vscode.commands.executeCommand<Array<vscode.CodeAction | vscode.Command>>(
+			'vscode.executeCodeActionProvider',
+			document.uri,
+			new vscode.Range(position, position),
+			undefined,
+			apply === undefined ? undefined : maxResolvedCodeActions
+		)) ?? [];
+	// Providers may still return plain commands, which run like a code action with only a command
+	const actions = results.map((result) => {
+		if (typeof result.command !== 'string') {
+			return result as vscode.CodeAction;
+		}
+		const action = new vscode.CodeAction(result.title);
+		action.command = result as vscode.Command;
+		return action;
+	});
+
+	if (apply === undefined) {
+		return {
+			success: true,
+			data: {
+				actions: actions.map((action) => ({
+					title: action.title,
+					kind: action.kind?.value ?? null,
+					isPreferred: action.isPreferred === true,
+				})),
+			},
+		};
+	}
+
+	const action = actions.find((candidate) => candidate.title === apply);
+	if (!action) {
+		const titles = actions.map((candidate) => `"${candidate.title}"`).join(', ') || 'none';
+		return { success: false, error: `No code action titled "${apply}". Available actions: ${titles}` };
+	}
+	const outcome = await applyCodeAction(action, dryRun, request.offsetEncoding);
+	if (outcome) {
+		return outcome;
+	}
+	const changedFiles = action.edit ? action.edit.entries().map(([uri]) => displayPath(uri)) : [];
+	return { success: true, data: { applied: action.title, changedFiles } };
+}
+
+// Converts hover contents to markdown, code blocks of the deprecated MarkedString form included
+function hoverMarkdown(content: vscode.MarkdownString | vscode.MarkedString): string {
+	if (typeof content === 'string') {
+		return content;
+	}
+	if ('language' in content) {
+		return `\`\`\`${content.language}\n${content.value}\n\`\`\``;
+	}
+	return content.value;
+}
+
+/**
+ * Returns the hover contents at a position as markdown, an empty string if there are none.
+ */
+export async function getHover(request: PositionRequest): Promise<ToolResult> {
+	const { document, position } = await resolvePosition(request);
+	const hovers =
+		(await vscode.commands.executeCommand<vscode.Hover[]>('vscode.executeHoverProvider', document.uri, position)) ??
+		[];
+	const text = hovers
+		.flatMap((hover) => hover.contents.map(hoverMarkdown))
+		.filter((markdown) => markdown.trim())
+		.join('\n\n');
+	return { success: true, data: text };
+}
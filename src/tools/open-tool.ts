@@ -5,7 +5,30 @@ import { promisify } from 'util';
 import type { Event, Uri } from 'vscode';
 import * as vscode from 'vscode';
 import { logger } from '../logger';
-import type { OpenDiffRequest, OpenFileRequest, OpenGitDiffRequest, OpenRequest } from './types';
+import {
+	encodedWorkspaceEditChanges,
+	type FileChange,
+	newFileEol,
+	resolveEol,
+	textEditsWithEol,
+	withLineEndings,
+} from './edits';
+import { gitAPI, repositoryFor, runGit } from './git-tools';
+import { fromEncodedPosition, toLineRange } from './positions';
+import type {
+	OpenCreateFileRequest,
+	OpenDiffRequest,
+	OpenFileRequest,
+	OpenFileState,
+	OpenFolderRequest,
+	OpenGitDiffRequest,
+	OpenInsertRequest,
+	OpenItemResult,
+	OpenNotebookRequest,
+	OpenRequest,
+	OpenRevealRequest,
+	ToolResult,
+} from './types';
 
 export type APIState = 'uninitialized' | 'initialized';
 
@@ -22,8 +45,21 @@ export interface GitAPI {
 	toGitUri(uri: Uri, ref: string): Uri;
 }
 
+export interface Change {
+	readonly uri: Uri;
+}
+
+export interface RepositoryState {
+	readonly indexChanges: Change[];
+	readonly workingTreeChanges: Change[];
+	readonly mergeChanges: Change[];
+	// Only reported by newer git extensions, older ones list untracked files as working tree changes
+	readonly untrackedChanges?: Change[];
+}
+
 export interface Repository {
 	rootUri: Uri;
+	readonly state: RepositoryState;
 }
 
 function findGitRoot(startPath: string): string | null {
@@ -64,131 +100,240 @@ export async function suggestOpenGitRoot(workspacePath: string, outputChannel: v
 
 const execPromise = promisify(exec);
 
+// Changed files a multi-file git diff opens at most unless maxFiles says otherwise
+const defaultMaxDiffFiles = 50;
+
+function toViewColumn(column: OpenFileRequest['viewColumn']): vscode.ViewColumn | undefined {
+	if (column === 'beside') return vscode.ViewColumn.Beside;
+	return column;
+}
+
+export function toRevealType(reveal: OpenFileRequest['reveal']): vscode.TextEditorRevealType {
+	switch (reveal) {
+		case 'top':
+			return vscode.TextEditorRevealType.AtTop;
+		case 'centerIfOutsideViewport':
+			return vscode.TextEditorRevealType.InCenterIfOutsideViewport;
+		default:
+			return vscode.TextEditorRevealType.InCenter;
+	}
+}
+
+// In-memory documents shown as the right side of diffs, keyed by URI. Each is
+// dropped once VS Code closes its document, so the content isn't kept forever.
+const virtualDocumentScheme = 'vs-claude-content';
+const virtualDocuments = new Map<string, string>();
+let virtualDocumentProvider: vscode.Disposable | undefined;
+let virtualDocumentCounter = 0;
+
+// Returns a read-only URI with the given content. The URI keeps the file name
+// of basedOn, so the document gets the same language.
+function virtualDocumentUri(basedOn: string, content: string): vscode.Uri {
+	if (!virtualDocumentProvider) {
+		virtualDocumentProvider = vscode.Disposable.from(
+			vscode.workspace.registerTextDocumentContentProvider(virtualDocumentScheme, {
+				provideTextDocumentContent: (uri) => virtualDocuments.get(uri.toString()) ?? '',
+			}),
+			vscode.workspace.onDidCloseTextDocument((document) => {
+				if (document.uri.scheme === virtualDocumentScheme) {
+					virtualDocuments.delete(document.uri.toString());
+				}
+			})
+		);
+	}
+	const uri = vscode.Uri.from({
+		scheme: virtualDocumentScheme,
+		path: `/${++virtualDocumentCounter}/${path.basename(basedOn)}`,
+	});
+	virtualDocuments.set(uri.toString(), content);
+	return uri;
+}
+
+// Opens a document, decoding it with the given encoding if set. The encoding
+// option requires VS Code 1.100+, older versions use the configured encoding.
+function openDocument(uri: vscode.Uri, encoding: string | undefined): Thenable<vscode.TextDocument> {
+	if (!encoding) return vscode.workspace.openTextDocument(uri);
+	const openTextDocument = vscode.workspace.openTextDocument as (
+		uri: vscode.Uri,
+		options: { encoding: string }
+	) => Thenable<vscode.TextDocument>;
+	return openTextDocument.call(vscode.workspace, uri, { encoding });
+}
+
+// Collapses everything, then unfolds the regions containing the given lines
+async function foldAllExcept(editor: vscode.TextEditor, lines: number[]): Promise<void> {
+	await vscode.commands.executeCommand('editor.foldAll');
+	await vscode.commands.executeCommand('editor.unfold', {
+		direction: 'up',
+		levels: Number.MAX_SAFE_INTEGER,
+		selectionLines: lines,
+	});
+	editor.revealRange(editor.selection, vscode.TextEditorRevealType.InCenterIfOutsideViewport);
+}
+
+// Returns the document a file item refers to: its uri if set, which may use any scheme
+// VS Code can open (untitled:, vscode-remote:, virtual file systems), otherwise its local path
+function fileUri(item: OpenFileRequest): vscode.Uri {
+	return item.uri ? vscode.Uri.parse(item.uri) : vscode.Uri.file(item.path ?? '');
+}
+
+// The UTF-8 byte order mark as it reads from a file decoded as UTF-8
+const byteOrderMark = '\uFEFF';
+
+// Returns the path of the active editor's file, for gitDiff items with active: true
+function activeEditorPath(): string {
+	const document = vscode.window.activeTextEditor?.document;
+	if (!document) {
+		throw new Error('No active editor');
+	}
+	if (document.uri.scheme !== 'file') {
+		throw new Error(`Active editor is not a file on disk: ${document.uri.toString()}`);
+	}
+	return document.uri.fsPath;
+}
+
 /**
  * This tool is used to open a file, diff, or git diff.
  */
 export class OpenHandler {
-	public async execute(items: OpenRequest[]): Promise<{ success: boolean; error?: string }> {
+	public async execute(
+		items: OpenRequest[]
+	): Promise<{ success: boolean; data?: OpenItemResult[]; error?: string }> {
 		logger.info('OpenHandler', `Opening ${items.length} items`);
 
-		// Track successes and failures
-		let successCount = 0;
-		const failedItems: Array<{ item: OpenRequest; error: string }> = [];
+		// One result per item, in input order
+		const results: OpenItemResult[] = new Array(items.length);
 
-		// Group file items by path to handle multiple highlights
-		const fileGroups = new Map<string, OpenFileRequest[]>();
-		const otherItems: OpenRequest[] = [];
+		// Group file items by document to handle multiple highlights
+		const fileGroups = new Map<string, Array<{ item: OpenFileRequest; index: number }>>();
+		const otherItems: Array<{ item: OpenRequest; index: number }> = [];
 
-		for (const item of items) {
+		items.forEach((item, index) => {
 			if (item.type === 'file') {
-				const existing = fileGroups.get(item.path) || [];
-				existing.push(item);
-				fileGroups.set(item.path, existing);
+				const key = fileUri(item).toString();
+				const existing = fileGroups.get(key) || [];
+				existing.push({ item, index });
+				fileGroups.set(key, existing);
 			} else {
-				otherItems.push(item);
+				otherItems.push({ item, index });
 			}
-		}
+		});
 
 		// Process grouped file items
-		for (const [path, fileItems] of fileGroups) {
+		for (const fileItems of fileGroups.values()) {
+			const path = fileItems[0].item.uri ?? fileItems[0].item.path ?? '';
 			try {
-				await this.openFileWithMultipleSelections(fileItems);
-				successCount += fileItems.length;
+				const state = await this.openFileWithMultipleSelections(fileItems.map(({ item }) => item));
+				for (const { index } of fileItems) {
+					results[index] = { success: true, state };
+				}
 			} catch (error) {
 				const errorMsg = this.formatFileError(path, error);
 				logger.error('OpenHandler', `Failed to open file ${path}: ${errorMsg}`);
-				for (const item of fileItems) {
-					failedItems.push({ item, error: errorMsg });
+				for (const { index } of fileItems) {
+					results[index] = { success: false, error: errorMsg };
 				}
 			}
 		}
 
 		// Process other items
-		for (const item of otherItems) {
+		for (const { item, index } of otherItems) {
 			try {
-				await this.openItem(item);
-				successCount++;
+				results[index] = { success: true, ...(await this.openItem(item)) };
 			} catch (error) {
 				const errorMsg = this.formatItemError(item, error);
 				logger.error('OpenHandler', `Failed to open item: ${errorMsg}`);
-				failedItems.push({ item, error: errorMsg });
+				results[index] = { success: false, error: errorMsg };
 			}
 		}
 
-		// Determine overall result
-		if (failedItems.length === 0) {
-			return { success: true };
-		} else if (successCount === 0) {
-			// All items failed
-			return {
-				success: false,
-				error: `Failed to open all ${items.length} items. First error: ${failedItems[0].error}`,
-			};
-		} else {
-			// Partial success
-			if (failedItems.length > 0) {
-				logger.warn(
-					'OpenHandler',
-					`Opened ${successCount}/${items.length} items (${failedItems.length} failed)`
-				);
+		// Overall success only if every item opened
+		const failed = results.filter((result) => !result.success);
+		if (failed.length === 0) {
+			return { success: true, data: results };
+		}
+		logger.warn('OpenHandler', `Opened ${items.length - failed.length}/${items.length} items (${failed.length} failed)`);
+		return {
+			success: false,
+			data: results,
+			error: `Failed to open ${failed.length} of ${items.length} items. First error: ${failed[0].error}`,
+		};
+	}
+
+	/**
+	 * Reports the changes the insert and createFile items would make. The other item types only show
+	 * things, so they are left out.
+	 */
+	public async dryRun(items: OpenRequest[]): Promise<ToolResult> {
+		const changes: Array<FileChange | { path: string; operation: 'create' }> = [];
+		for (const item of items) {
+			try {
+				if (item.type === 'insert') {
+					const { edit } = await this.insertEdit(item);
+					changes.push(...(await encodedWorkspaceEditChanges(edit, item.offsetEncoding)));
+				} else if (item.type === 'createFile') {
+					const { content } = this.createFileContent(item);
+					if (!fs.existsSync(item.path)) {
+						changes.push({ path: item.path, operation: 'create' });
+						continue;
+					}
+					// Replacing a file shows as one edit over its whole text, a BOM is not part of it
+					const document = await vscode.workspace.openTextDocument(vscode.Uri.file(item.path));
+					const end = document.lineAt(document.lineCount - 1).range.end;
+					const range = toLineRange(new vscode.Range(new vscode.Position(0, 0), end));
+					changes.push({ path: item.path, edits: [{ range, newText: content }] });
+				}
+			} catch (error) {
+				return { success: false, error: this.formatItemError(item, error) };
 			}
-			return {
-				success: true, // Return success if at least one item opened
-			};
 		}
+		return { success: true, data: { dryRun: true, changes } };
 	}
 
-	private async openItem(item: OpenRequest): Promise<void> {
+	// Returns what the item reports besides its success, like the repository of git diffs
+	private async openItem(item: OpenRequest): Promise<Omit<OpenItemResult, 'success'>> {
 		switch (item.type) {
 			case 'file':
-				await this.openFile(item);
-				break;
+				return { state: await this.openFileWithMultipleSelections([item]) };
 			case 'diff':
 				await this.openDiff(item);
 				break;
-			case 'gitDiff':
-				await this.openGitDiff(item);
+			case 'gitDiff': {
+				const repository = item.changedOnly
+					? await this.openChangedFiles(item)
+					: await this.openGitDiff({ ...item, path: item.active ? activeEditorPath() : (item.path ?? '') });
+				return { repository };
+			}
+			case 'reveal':
+				await this.reveal(item);
+				break;
+			case 'openFolder':
+				await this.openFolder(item);
+				break;
+			case 'insert':
+				return { version: await this.insert(item) };
+			case 'createFile':
+				await this.createFile(item);
+				break;
+			case 'notebook':
+				await this.openNotebook(item);
 				break;
 			default:
 				throw new Error(`Unknown item type: ${(item as OpenRequest).type}`);
 		}
+		return {};
 	}
 
-	private async openFile(item: OpenFileRequest): Promise<void> {
-		const uri = vscode.Uri.file(item.path);
-		logger.debug('OpenHandler', `Opening file: ${item.path}`);
-		const doc = await vscode.workspace.openTextDocument(uri);
-
-		// Use the preview property from the item, defaulting to false
-		const editor = await vscode.window.showTextDocument(doc, {
-			preview: item.preview ?? false, // Use item.preview if specified, otherwise default to false
-			preserveFocus: item.preview === true, // Keep focus on current editor if preview mode
-		});
-
-		if (item.startLine) {
-			const startLine = item.startLine - 1;
-			const endLine = item.endLine ? item.endLine - 1 : startLine;
-
-			const startPos = new vscode.Position(startLine, 0);
-			const endLineLength = doc.lineAt(endLine).text.length;
-			const endPos = new vscode.Position(endLine, endLineLength);
-
-			const range = new vscode.Range(startPos, endPos);
-			editor.selection = new vscode.Selection(startPos, endPos);
-			editor.revealRange(range, vscode.TextEditorRevealType.InCenter);
-		}
-	}
-
-	private async openFileWithMultipleSelections(items: OpenFileRequest[]): Promise<void> {
-		if (items.length === 0) return;
-
-		const uri = vscode.Uri.file(items[0].path);
-		const doc = await vscode.workspace.openTextDocument(uri);
+	// Opens the file of one or more file items, with a selection for each item's line range
+	private async openFileWithMultipleSelections(items: OpenFileRequest[]): Promise<OpenFileState> {
+		const uri = fileUri(items[0]);
+		const doc = await openDocument(uri, items.find((item) => item.encoding)?.encoding);
 
 		// Use the preview property from the first item
 		const editor = await vscode.window.showTextDocument(doc, {
 			preview: items[0].preview ?? false,
 			preserveFocus: items[0].preview === true,
+			viewColumn: toViewColumn(items[0].viewColumn),
 		});
 
 		// Create selections for all items with line ranges
@@ -197,8 +342,10 @@ export class OpenHandler {
 
 		for (const item of items) {
 			if (item.startLine) {
-				const startLine = item.startLine - 1;
-				const endLine = item.endLine ? item.endLine - 1 : startLine;
+				// Clamp lines past the end of the document to its last line
+				const lastLine = doc.lineCount - 1;
+				const startLine = Math.min(item.startLine - 1, lastLine);
+				const endLine = Math.min(item.endLine ? item.endLine - 1 : startLine, lastLine);
 
 				const startPos = new vscode.Position(startLine, 0);
 				const endLineLength = doc.lineAt(endLine).text.length;
@@ -218,26 +365,225 @@ export class OpenHandler {
 
 			// Reveal the first range
 			if (firstRange) {
-				editor.revealRange(firstRange, vscode.TextEditorRevealType.InCenter);
+				editor.revealRange(firstRange, toRevealType(items[0].reveal));
 			}
+
+			// Fold everything but the selected lines
+			if (items.some((item) => item.fold)) {
+				const lines: number[] = [];
+				for (const selection of selections) {
+					for (let line = selection.start.line; line <= selection.end.line; line++) {
+						lines.push(line);
+					}
+				}
+				await foldAllExcept(editor, lines);
+			}
+		}
+
+		if (items.some((item) => item.newWindow)) {
+			// Opens the active editor's file in a new window, which loads it on its own
+			await vscode.commands.executeCommand('workbench.action.files.showOpenedFileInNewWindow');
 		}
+
+		return {
+			lineCount: doc.lineCount,
+			selections: editor.selections.map(toLineRange),
+			visibleRange: editor.visibleRanges.length > 0 ? toLineRange(editor.visibleRanges[0]) : undefined,
+		};
 	}
 
 	private async openDiff(item: OpenDiffRequest): Promise<void> {
 		const leftUri = vscode.Uri.file(item.left);
-		const rightUri = vscode.Uri.file(item.right);
-		logger.debug('OpenHandler', `Opening diff: ${item.left} ↔ ${item.right}`);
+		const rightUri =
+			item.rightContent !== undefined
+				? virtualDocumentUri(item.left, item.rightContent)
+				: vscode.Uri.file(item.right ?? '');
+		const rightName = item.rightContent !== undefined ? 'proposed' : path.basename(item.right ?? '');
+		logger.debug('OpenHandler', `Opening diff: ${item.left} ↔ ${rightUri.toString()}`);
 
 		await vscode.commands.executeCommand(
 			'vscode.diff',
 			leftUri,
 			rightUri,
-			item.title || `${path.basename(item.left)} ↔ ${path.basename(item.right)}`,
+			item.title || `${path.basename(item.left)} ↔ ${rightName}`,
 			{ preview: false } // Don't open in preview mode
 		);
 	}
 
-	private async openGitDiff(item: OpenGitDiffRequest): Promise<void> {
+	private async reveal(item: OpenRevealRequest): Promise<void> {
+		const uri = vscode.Uri.file(item.path);
+		// The Explorer only shows the workspace folders, revealing anything else silently does nothing
+		if (!vscode.workspace.getWorkspaceFolder(uri)) {
+			throw new Error(`Path is outside all workspace folders: ${item.path}`);
+		}
+		if (!fs.existsSync(item.path)) {
+			throw new Error(`File not found: ${item.path}`);
+		}
+		logger.debug('OpenHandler', `Revealing in Explorer: ${item.path}`);
+		await vscode.commands.executeCommand('revealInExplorer', uri);
+	}
+
+	private async openFolder(item: OpenFolderRequest): Promise<void> {
+		if (!fs.existsSync(item.path)) {
+			throw new Error(`Folder not found: ${item.path}`);
+		}
+		const uri = vscode.Uri.file(item.path);
+		logger.debug('OpenHandler', `Opening folder: ${item.path} (new window: ${item.newWindow === true})`);
+		if (item.newWindow) {
+			await vscode.commands.executeCommand('vscode.openFolder', uri, { forceNewWindow: true });
+			return;
+		}
+		// Replacing the folder restarts this extension, give the response a moment to be written first
+		setTimeout(() => {
+			vscode.commands.executeCommand('vscode.openFolder', uri, { forceNewWindow: false });
+		}, 200);
+	}
+
+	// Inserts text in the document as one edit and returns the document's new version
+	// Returns the workspace edit of an insert item, and the position the text goes to
+	private async insertEdit(
+		item: OpenInsertRequest
+	): Promise<{ document: vscode.TextDocument; position: vscode.Position; edit: vscode.WorkspaceEdit }> {
+		const document = await vscode.workspace.openTextDocument(vscode.Uri.file(item.path));
+		const requested = fromEncodedPosition(document, item.line, item.character, item.offsetEncoding);
+		const position = document.validatePosition(requested);
+		const insertion = { range: new vscode.Range(position, position), newText: item.text };
+		const edit = new vscode.WorkspaceEdit();
+		edit.set(document.uri, textEditsWithEol(document, [insertion], item.eol));
+		return { document, position, edit };
+	}
+
+	private async insert(item: OpenInsertRequest): Promise<number> {
+		const { document, position, edit } = await this.insertEdit(item);
+		const textEdits = edit.get(document.uri);
+		if (!(await vscode.workspace.applyEdit(edit))) {
+			throw new Error('VS Code refused the edit, e.g. because the file is read-only');
+		}
+
+		if (item.select) {
+			// The selection ends on the inserted text's last line, after its last character
+			const lines = textEdits[0].newText.split(/\r?\n/);
+			const last = lines[lines.length - 1];
+			const end =
+				lines.length === 1
+					? position.translate(0, last.length)
+					: new vscode.Position(position.line + lines.length - 1, last.length);
+			const editor = await vscode.window.showTextDocument(document, { preview: false });
+			editor.selection = new vscode.Selection(position, end);
+			editor.revealRange(editor.selection, vscode.TextEditorRevealType.InCenterIfOutsideViewport);
+		}
+		return document.version;
+	}
+
+	// Returns the text a createFile item writes, without a BOM, and whether to write one
+	private createFileContent(item: OpenCreateFileRequest): { content: string; bom: boolean } {
+		const exists = fs.existsSync(item.path);
+		if (!item.overwrite && exists) {
+			throw new Error('File already exists, pass overwrite: true to replace it');
+		}
+		// A replaced file keeps its BOM and, with eol auto, its line endings
+		const previous = exists ? fs.readFileSync(item.path, 'utf8') : undefined;
+		let fallbackEol = newFileEol(vscode.Uri.file(item.path));
+		if (previous !== undefined) {
+			fallbackEol = previous.includes('\r\n') ? vscode.EndOfLine.CRLF : vscode.EndOfLine.LF;
+		}
+		const bom = previous === undefined ? item.bom === true : previous.startsWith(byteOrderMark);
+		const content = withLineEndings(item.content.replace(/^\uFEFF/, ''), resolveEol(item.eol, fallbackEol));
+		return { content, bom };
+	}
+
+	private async createFile(item: OpenCreateFileRequest): Promise<void> {
+		const { content, bom } = this.createFileContent(item);
+		logger.debug('OpenHandler', `Creating file: ${item.path} (${content.length} characters)`);
+		await fs.promises.mkdir(path.dirname(item.path), { recursive: true });
+		await fs.promises.writeFile(item.path, bom ? byteOrderMark + content : content, 'utf8');
+
+		const document = await vscode.workspace.openTextDocument(vscode.Uri.file(item.path));
+		await vscode.window.showTextDocument(document, { preview: false });
+	}
+
+	// Opens a multi-file diff of every file changed between the item's from and to
+	private async openNotebook(item: OpenNotebookRequest): Promise<void> {
+		if (!fs.existsSync(item.path)) {
+			throw new Error(`File not found: ${item.path}`);
+		}
+		const uri = vscode.Uri.file(item.path);
+		logger.debug('OpenHandler', `Opening notebook: ${item.path}`);
+
+		let editor: vscode.NotebookEditor | undefined;
+		if (item.notebookType) {
+			// openNotebookDocument picks the provider itself, opening with a given one goes through the editor
+			await vscode.commands.executeCommand('vscode.openWith', uri, item.notebookType, { preview: false });
+			editor = vscode.window.visibleNotebookEditors.find(
+				(candidate) => candidate.notebook.uri.toString() === uri.toString()
+			);
+			if (!editor) {
+				throw new Error(`No notebook provider '${item.notebookType}' opened the file`);
+			}
+		} else {
+			const notebook = await vscode.workspace.openNotebookDocument(uri);
+			editor = await vscode.window.showNotebookDocument(notebook, { preview: false });
+		}
+
+		if (item.cell !== undefined) {
+			const cellCount = editor.notebook.cellCount;
+			if (item.cell >= cellCount) {
+				throw new Error(`Cell ${item.cell} is out of range, the notebook has ${cellCount} cells`);
+			}
+			const range = new vscode.NotebookRange(item.cell, item.cell + 1);
+			editor.selections = [range];
+			editor.revealRange(range, vscode.NotebookEditorRevealType.InCenter);
+		}
+	}
+
+	private async openChangedFiles(item: OpenGitDiffRequest): Promise<string> {
+		const git = await gitAPI();
+		let repo: Repository;
+		if (item.path) {
+			repo = await repositoryFor(item.path);
+		} else if (git.repositories.length === 1) {
+			repo = git.repositories[0];
+		} else if (git.repositories.length === 0) {
+			throw new Error('No git repositories found in the current workspace');
+		} else {
+			const roots = git.repositories.map((r) => r.rootUri.fsPath).join(', ');
+			throw new Error(`Multiple git repositories are open (${roots}), pass a path to select one`);
+		}
+		const root = repo.rootUri.fsPath;
+
+		// The set of changed files doesn't depend on the direction, only on what is compared
+		const sides = [item.from, item.to];
+		const refs = sides.filter((side) => side !== 'working' && side !== 'staged');
+		const diffArgs = sides.includes('staged') && !sides.includes('working') ? ['--cached', ...refs] : refs;
+		const output = await runGit(root, ['diff', '--name-only', '--no-renames', '-z', ...diffArgs]);
+		const files = output.split('\0').filter((file) => file);
+
+		const maxFiles = item.maxFiles ?? defaultMaxDiffFiles;
+		if (files.length > maxFiles) {
+			throw new Error(
+				`${files.length} files changed between ${item.from} and ${item.to}, more than maxFiles (${maxFiles})`
+			);
+		}
+		if (files.length === 0) {
+			throw new Error(`No files changed between ${item.from} and ${item.to}`);
+		}
+
+		const sideUri = (fileUri: vscode.Uri, side: string) =>
+			side === 'working' ? fileUri : git.toGitUri(fileUri, side === 'staged' ? '' : side);
+		const resources = files.map((file) => {
+			const fileUri = vscode.Uri.file(path.join(root, file));
+			return [fileUri, sideUri(fileUri, item.from), sideUri(fileUri, item.to)];
+		});
+		logger.debug('OpenHandler', `Opening ${files.length} changed files (${item.from} → ${item.to})`);
+		await vscode.commands.executeCommand(
+			'vscode.changes',
+			`${path.basename(root)} (${item.from} ↔ ${item.to})`,
+			resources
+		);
+		return root;
+	}
+
+	private async openGitDiff(item: OpenGitDiffRequest & { path: string }): Promise<string> {
 		logger.debug('OpenHandler', `Opening git diff: ${item.path} (${item.from} → ${item.to})`);
 
 		// Get git extension
@@ -380,6 +726,18 @@ export class OpenHandler {
 			logger.warn('OpenHandler', `Failed to verify refs: ${error}`);
 		}
 
+		// Comparing against the index only makes sense if the file is in it
+		if (item.from === 'staged' || item.to === 'staged') {
+			const relativePath = path.relative(repo.rootUri.fsPath, item.path).split(path.sep).join('/');
+			try {
+				await execPromise(`git cat-file -e ${JSON.stringify(`:${relativePath}`)}`, {
+					cwd: repo.rootUri.fsPath,
+				});
+			} catch (_e) {
+				throw new Error(`File has no staged version: ${item.path}`);
+			}
+		}
+
 		await vscode.commands.executeCommand(
 			'vscode.diff',
 			leftUri,
@@ -387,6 +745,8 @@ export class OpenHandler {
 			`${path.basename(item.path)} (${item.from} ↔ ${item.to})`,
 			{ preview: false } // Don't open in preview mode
 		);
+
+		return repo.rootUri.fsPath;
 	}
 
 	private formatFileError(path: string, error: unknown): string {
@@ -409,11 +769,23 @@ export class OpenHandler {
 
 		switch (item.type) {
 			case 'file':
-				return this.formatFileError(item.path, error);
+				return this.formatFileError(item.uri ?? item.path ?? '', error);
 			case 'diff':
-				return `Failed to open diff (${item.left} ↔ ${item.right}): ${errorStr}`;
-			case 'gitDiff':
-				return `Failed to open git diff for ${item.path}: ${errorStr}`;
+				return `Failed to open diff (${item.left} ↔ ${item.right ?? 'rightContent'}): ${errorStr}`;
+			case 'gitDiff': {
+				const target = item.path ?? (item.changedOnly ? 'the changed files' : 'the active editor');
+				return `Failed to open git diff for ${target}: ${errorStr}`;
+			}
+			case 'reveal':
+				return `Failed to reveal ${item.path}: ${errorStr}`;
+			case 'openFolder':
+				return `Failed to open folder ${item.path}: ${errorStr}`;
+			case 'insert':
+				return `Failed to insert into ${item.path}: ${errorStr}`;
+			case 'createFile':
+				return `Failed to create ${item.path}: ${errorStr}`;
+			case 'notebook':
+				return `Failed to open notebook ${item.path}: ${errorStr}`;
 			default:
 				return errorStr;
 		}
@@ -0,0 +1,100 @@
+import * as vscode from 'vscode';
+import type { LineRange, OffsetEncoding } from './types';
+
+// Converts a range to 1-based lines and 0-based characters
+export function toLineRange(range: vscode.Range): LineRange {
+	return {
+		startLine: range.start.line + 1,
+		startCharacter: range.start.character,
+		endLine: range.end.line + 1,
+		endCharacter: range.end.character,
+	};
+}
+
+// Converts a character offset in a line from the given encoding to UTF-16 code units. An offset inside a
+// character lands after it, one past the end of the line stays past the end so validation catches it
+export function toUtf16Offset(lineText: string, offset: number, encoding: OffsetEncoding | undefined): number {
+	if (encoding === undefined || encoding === 'utf16') {
+		return offset;
+	}
+	let counted = 0;
+	let units = 0;
+	for (const char of lineText) {
+		if (counted >= offset) {
+			return units;
+		}
+		counted += encoding === 'utf8' ? Buffer.byteLength(char, 'utf8') : 1;
+		units += char.length;
+	}
+	return units + Math.max(0, offset - counted);
+}
+
+// Converts a character offset in a line from UTF-16 code units to the given encoding
+export function fromUtf16Offset(lineText: string, character: number, encoding: OffsetEncoding | undefined): number {
+	if (encoding === undefined || encoding === 'utf16') {
+		return character;
+	}
+	const prefix = lineText.slice(0, character);
+	return encoding === 'utf8' ? Buffer.byteLength(prefix, 'utf8') : [...prefix].length;
+}
+
+// Converts a 1-based line and a character in the given encoding to a position, without clamping it
+// to the document
+export function fromEncodedPosition(
+	document: vscode.TextDocument,
+	line: number,
+	character: number,
+	encoding: OffsetEncoding | undefined
+): vscode.Position {
+	const text = line >= 1 && line <= document.lineCount ? document.lineAt(line - 1).text : '';
+	return new vscode.Position(line - 1, toUtf16Offset(text, character, encoding));
+}
+
+// Converts 1-based lines and characters in the given encoding to a range, without clamping it
+export function fromEncodedLineRange(
+	document: vscode.TextDocument,
+	range: LineRange,
+	encoding: OffsetEncoding | undefined
+): vscode.Range {
+	return new vscode.Range(
+		fromEncodedPosition(document, range.startLine, range.startCharacter, encoding),
+		fromEncodedPosition(document, range.endLine, range.endCharacter, encoding)
+	);
+}
+
+// Converts a position's character to the given encoding
+export function toEncodedCharacter(
+	document: vscode.TextDocument,
+	position: vscode.Position,
+	encoding: OffsetEncoding | undefined
+): number {
+	// Ranges may end at the start of the line after the last one
+	const text = position.line < document.lineCount ? document.lineAt(position.line).text : '';
+	return fromUtf16Offset(text, position.character, encoding);
+}
+
+// Converts a range to 1-based lines and characters in the given encoding
+export function toEncodedLineRange(
+	document: vscode.TextDocument,
+	range: vscode.Range,
+	encoding: OffsetEncoding | undefined
+): LineRange {
+	return {
+		startLine: range.start.line + 1,
+		startCharacter: toEncodedCharacter(document, range.start, encoding),
+		endLine: range.end.line + 1,
+		endCharacter: toEncodedCharacter(document, range.end, encoding),
+	};
+}
+
+// Converts a range of a file like toEncodedLineRange, opening the file only if characters need converting
+export async function toEncodedLineRangeIn(
+	uri: vscode.Uri,
+	range: vscode.Range,
+	encoding: OffsetEncoding | undefined
+): Promise<LineRange> {
+	if (encoding === undefined || encoding === 'utf16') {
+		return toLineRange(range);
+	}
+	return toEncodedLineRange(await vscode.workspace.openTextDocument(uri), range, encoding);
+}
@@ -0,0 +1,198 @@
+import { spawn } from 'child_process';
+import * as fs from 'fs';
+import * as path from 'path';
+import * as vscode from 'vscode';
+import { logger } from '../logger';
+import type { ToolResult } from './types';
+
+// A match with a 1-based line and 0-based character, in UTF-16 code units like all VS Code positions
+export interface TextMatch {
+	path: string;
+	line: number;
+	character: number;
+	text: string;
+	match: string;
+}
+
+// Matched lines longer than this are cut in results
+const maxLineLength = 500;
+
+// Returns the ripgrep binary VS Code's own search runs
+function findRipgrep(): string {
+	const binary = process.platform === 'win32' ? 'rg.exe' : 'rg';
+	const candidates = ['node_modules', 'node_modules.asar.unpacked'].flatMap((modules) => [
+		path.join(vscode.env.appRoot, modules, '@vscode', 'ripgrep', 'bin', binary),
+		path.join(vscode.env.appRoot, modules, 'vscode-ripgrep', 'bin', binary),
+	]);
+	const found = candidates.find((candidate) => fs.existsSync(candidate));
+	if (!found) {
+		throw new Error(`VS Code's ripgrep binary was not found in ${vscode.env.appRoot}`);
+	}
+	return found;
+}
+
+// Globs excluded from search by files.exclude and search.exclude, like in the Search view
+function excludedGlobs(): string[] {
+	const globs: string[] = [];
+	for (const section of ['files', 'search']) {
+		const excludes = vscode.workspace.getConfiguration(section).get<Record<string, unknown>>('exclude') ?? {};
+		globs.push(...Object.keys(excludes).filter((glob) => excludes[glob] === true));
+	}
+	return globs;
+}
+
+export interface SearchOptions {
+	pattern: string;
+	regex: boolean;
+	// Match whole words only
+	wordRegexp?: boolean;
+	include?: string;
+	maxResults: number;
+}
+
+/**
+ * Searches the workspace folders with ripgrep the way the Search view does, respecting .gitignore,
+ * files.exclude and search.exclude. Stops once more than maxResults matches were found.
+ */
+export async function searchWorkspace(options: SearchOptions): Promise<{ matches: TextMatch[]; truncated: boolean }> {
+	const folders = (vscode.workspace.workspaceFolders ?? [])
+		.filter((folder) => folder.uri.scheme === 'file')
+		.map((folder) => folder.uri.fsPath);
+	if (folders.length === 0) {
+		throw new Error('No folder open in VS Code, there is nothing to search');
+	}
+
+	const args = ['--json', '--hidden', options.regex ? '--auto-hybrid-regex' : '--fixed-strings'];
+	if (options.wordRegexp) {
+		args.push('--word-regexp');
+	}
+	if (options.include) {
+		args.push('--glob', options.include);
+	}
+	for (const glob of excludedGlobs()) {
+		args.push('--glob', `!${glob}`);
+	}
+	args.push('--regexp', options.pattern, '--', ...folders);
+
+	const rg = findRipgrep();
+	logger.debug('SearchTools', `Searching for ${options.pattern} in ${folders.join(', ')}`);
+	return new Promise((resolve, reject) => {
+		const child = spawn(rg, args, { cwd: folders[0] });
+		const matches: TextMatch[] = [];
+		let truncated = false;
+		let pending = '';
+		let stderr = '';
+
+		const handleLine = (line: string) => {
+			if (!line || truncated) {
+				return;
+			}
+			const event = JSON.parse(line);
+			// Paths and lines that aren't valid UTF-8 come as bytes, they are skipped
+			if (event.type !== 'match' || event.data.path.text === undefined || event.data.lines.text === undefined) {
+				return;
+			}
+			const text: string = event.data.lines.text.replace(/\r?\n$/, '');
+			const bytes = Buffer.from(text, 'utf8');
+			for (const submatch of event.data.submatches) {
+				if (matches.length === options.maxResults) {
+					truncated = true;
+					child.kill();
+					return;
+				}
+				matches.push({
+					path: event.data.path.text,
+					line: event.data.line_number,
+					// ripgrep reports byte offsets
+					character: bytes.subarray(0, submatch.start).toString('utf8').length,
+					text: text.length > maxLineLength ? `${text.slice(0, maxLineLength)}…` : text,
+					match: submatch.match.text ?? '',
+				});
+			}
+		};
+
+		child.stdout.on('data', (chunk: Buffer) => {
+			const lines = (pending + chunk.toString('utf8')).split('\n');
+			pending = lines.pop() ?? '';
+			for (const line of lines) {
+				handleLine(line);
+			}
+		});
+		child.stderr.on('data', (chunk: Buffer) => {
+			stderr += chunk.toString('utf8');
+		});
+		child.on('error', reject);
+		child.on('close', (code) => {
+			handleLine(pending);
+			// 1 means nothing was found, 2 an error that may still come with matches, e.g. an unreadable file
+			if (!truncated && code === 2 && matches.length === 0) {
+				reject(new Error(`Search failed: ${stderr.trim()}`));
+				return;
+			}
+			resolve({ matches, truncated });
+		});
+	});
+}
+
+export interface SearchRequest {
+	query: string;
+	regex?: boolean;
+	includeGlob?: string;
+	maxResults?: number;
+}
+
+/**
+ * Searches the workspace for text or a regular expression.
+ */
+export async function search({
+	query,
+	regex = false,
+	includeGlob,
+	maxResults = 100,
+}: SearchRequest): Promise<ToolResult> {
+	const { matches, truncated } = await searchWorkspace({ pattern: query, regex, include: includeGlob, maxResults });
+	return {
+		success: true,
+		data: {
+			matches: matches.map(({ path, line, character, text }) => ({ path, line, character, text })),
+			truncated,
+		},
+	};
+}
+
+export interface ListTodosRequest {
+	include?: string;
+	tags?: string[];
+	maxResults?: number;
+}
+
+/**
+ * Lists the comment tags like TODO and FIXME in the workspace.
+ */
+export async function listTodos({
+	include,
+	tags = ['TODO', 'FIXME'],
+	maxResults = 100,
+}: ListTodosRequest): Promise<ToolResult> {
+	const pattern = tags.map((tag) => tag.replace(/[.*+?^${}()|[\]\\]/g, '\\$&')).join('|');
+	const { matches, truncated } = await searchWorkspace({
+		pattern,
+		regex: true,
+		wordRegexp: true,
+		include,
+		maxResults,
+	});
+	return {
+		success: true,
+		data: {
+			todos: matches.map(({ path, line, character, text, match }) => ({
+				path,
+				line,
+				character,
+				tag: match,
+				text: text.trim(),
+			})),
+			truncated,
+		},
+	};
+}
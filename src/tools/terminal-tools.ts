@@ -0,0 +1,144 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import * as vscode from 'vscode';
+import type { ToolResult } from './types';
+
+export interface RunScriptRequest {
+	name?: string;
+	folder?: string;
+}
+
+interface Script {
+	name: string;
+	source: 'package.json' | 'Makefile' | 'go';
+	command: string;
+	cwd: string;
+}
+
+// Picks the package manager whose lock file is in the folder
+function packageManager(folder: string): string {
+	if (fs.existsSync(path.join(folder, 'pnpm-lock.yaml'))) {
+		return 'pnpm';
+	}
+	if (fs.existsSync(path.join(folder, 'yarn.lock'))) {
+		return 'yarn';
+	}
+	return 'npm';
+}
+
+function packageScripts(folder: string): Script[] {
+	const manifest = path.join(folder, 'package.json');
+	if (!fs.existsSync(manifest)) {
+		return [];
+	}
+	const scripts: Record<string, string> = JSON.parse(fs.readFileSync(manifest, 'utf8')).scripts ?? {};
+	const manager = packageManager(folder);
+	return Object.keys(scripts).map(
+		(name): Script => ({ name, source: 'package.json', command: `${manager} run ${name}`, cwd: folder })
+	);
+}
+
+function makeTargets(folder: string): Script[] {
+	const makefile = ['Makefile', 'makefile', 'GNUmakefile']
+		.map((name) => path.join(folder, name))
+		.find((file) => fs.existsSync(file));
+	if (!makefile) {
+		return [];
+	}
+	// Rule lines like "build: deps", skipping variable assignments (:=) and special targets (.PHONY)
+	const targets = new Set<string>();
+	for (const match of fs.readFileSync(makefile, 'utf8').matchAll(/^([A-Za-z0-9_][\w.-]*)\s*:(?!=)/gm)) {
+		targets.add(match[1]);
+	}
+	return [...targets].map((name): Script => ({ name, source: 'Makefile', command: `make ${name}`, cwd: folder }));
+}
+
+async function goMainPackages(folder: string): Promise<Script[]> {
+	if (!fs.existsSync(path.join(folder, 'go.mod'))) {
+		return [];
+	}
+	const files = await vscode.workspace.findFiles(
+		new vscode.RelativePattern(folder, '**/*.go'),
+		'**/{vendor,testdata,node_modules}/**',
+		5000
+	);
+	const dirs = new Set<string>();
+	for (const file of files) {
+		if (file.fsPath.endsWith('_test.go') || dirs.has(path.dirname(file.fsPath))) {
+			continue;
+		}
+		if (/^package main\b/m.test(fs.readFileSync(file.fsPath, 'utf8'))) {
+			dirs.add(path.dirname(file.fsPath));
+		}
+	}
+	return [...dirs].sort().map((dir): Script => {
+		const relative = path.relative(folder, dir).split(path.sep).join('/');
+		const name = relative ? `./${relative}` : '.';
+		return { name, source: 'go', command: `go run ${name}`, cwd: folder };
+	});
+}
+
+async function detectScripts(folders: string[]): Promise<Script[]> {
+	const scripts: Script[] = [];
+	for (const folder of folders) {
+		scripts.push(...packageScripts(folder), ...makeTargets(folder), ...(await goMainPackages(folder)));
+	}
+	return scripts;
+}
+
+/**
+ * Lists the scripts of the workspace folders, or runs one of them in a new integrated terminal.
+ */
+export async function runScript({ name, folder }: RunScriptRequest): Promise<ToolResult> {
+	const folders = folder
+		? [folder]
+		: (vscode.workspace.workspaceFolders ?? [])
+				.filter((workspaceFolder) => workspaceFolder.uri.scheme === 'file')
+				.map((workspaceFolder) => workspaceFolder.uri.fsPath);
+	const scripts = await detectScripts(folders);
+	if (name === undefined) {
+		return { success: true, data: { scripts } };
+	}
+
+	const script = scripts.find((candidate) => candidate.name === name);
+	if (!script) {
+		const available = scripts.map((candidate) => `${candidate.name} (${candidate.source})`).join(', ') || 'none';
+		return { success: false, error: `Script not found: ${name}. Available scripts: ${available}` };
+	}
+
+	const terminal = vscode.window.createTerminal({ name: script.name, cwd: script.cwd });
+	terminal.show(true);
+	terminal.sendText(script.command);
+	return { success: true, data: { started: true, name: script.name, command: script.command, cwd: script.cwd } };
+}
+
+export interface TerminalRequest {
+	command: string;
+	cwd?: string;
+	name?: string;
+	show?: boolean;
+	reuse?: boolean;
+}
+
+/**
+ * Sends a shell command to an integrated terminal, creating one unless an existing one is reused.
+ */
+export async function terminal({
+	command,
+	cwd,
+	name = 'vs-claude',
+	show = true,
+	reuse,
+}: TerminalRequest): Promise<ToolResult> {
+	let target = reuse ? vscode.window.terminals.find((candidate) => candidate.name === name) : undefined;
+	const created = target === undefined;
+	if (!target) {
+		target = vscode.window.createTerminal({ name, cwd });
+	}
+	if (show) {
+		// Keep the focus in the editor, the user didn't type the command
+		target.show(true);
+	}
+	target.sendText(command);
+	return { success: true, data: { sent: true, terminal: name, created } };
+}
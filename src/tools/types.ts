@@ -1,27 +1,133 @@
 export interface OpenFileRequest {
 	type: 'file';
-	path: string;
+	// Exactly one of path (a local file) or uri (any VS Code URI) is used, uri wins
+	path?: string;
+	uri?: string;
 	startLine?: number;
 	endLine?: number;
 	preview?: boolean;
+	// Move the file to a new window once it is open
+	newWindow?: boolean;
+	viewColumn?: 1 | 2 | 3 | 'beside';
+	reveal?: 'center' | 'top' | 'centerIfOutsideViewport';
+	fold?: boolean;
+	encoding?: string;
 }
 
 export interface OpenDiffRequest {
 	type: 'diff';
 	left: string;
-	right: string;
+	// Exactly one of right (a file) or rightContent (in-memory text) is set
+	right?: string;
+	rightContent?: string;
 	title?: string;
 }
 
 export interface OpenGitDiffRequest {
 	type: 'gitDiff';
-	path: string;
+	// Exactly one of path or active (the active editor's file) is set, unless changedOnly is
+	path?: string;
+	active?: boolean;
 	from: string;
 	to: string;
 	context?: number;
+	// Diff every file changed between from and to, path optionally selects the repository
+	changedOnly?: boolean;
+	maxFiles?: number;
+}
+
+export interface OpenRevealRequest {
+	type: 'reveal';
+	// A file or folder inside one of the workspace folders
+	path: string;
+}
+
+export interface OpenFolderRequest {
+	type: 'openFolder';
+	path: string;
+	// Open in a new window instead of replacing the current window's folder
+	newWindow?: boolean;
+}
+
+// Line endings of written text: auto keeps the document's, or uses files.eol for new files
+export type EolMode = 'lf' | 'crlf' | 'auto';
+
+// Unit of the character offsets exchanged with the MCP server, VS Code's own are UTF-16 code units
+export type OffsetEncoding = 'utf16' | 'utf8' | 'codepoint';
+
+export interface OpenInsertRequest {
+	type: 'insert';
+	path: string;
+	// 1-based line and 0-based character
+	line: number;
+	character: number;
+	text: string;
+	// Select the inserted text in the editor
+	select?: boolean;
+	eol?: EolMode;
+	// Unit of character, UTF-16 code units by default
+	offsetEncoding?: OffsetEncoding;
 }
 
-export type OpenRequest = OpenFileRequest | OpenDiffRequest | OpenGitDiffRequest;
+export interface OpenCreateFileRequest {
+	type: 'createFile';
+	path: string;
+	content: string;
+	// Replace the file if it exists instead of failing
+	overwrite?: boolean;
+	eol?: EolMode;
+	// Start a new file with a UTF-8 BOM, an existing file's BOM is kept regardless
+	bom?: boolean;
+}
+
+export interface OpenNotebookRequest {
+	type: 'notebook';
+	path: string;
+	// 0-based cell to select and scroll to
+	cell?: number;
+	// Notebook provider to open the file with, needed for files other than .ipynb
+	notebookType?: string;
+}
+
+export type OpenRequest =
+	| OpenFileRequest
+	| OpenDiffRequest
+	| OpenGitDiffRequest
+	| OpenRevealRequest
+	| OpenFolderRequest
+	| OpenInsertRequest
+	| OpenCreateFileRequest
+	| OpenNotebookRequest;
+
+// Line range with 1-based lines and 0-based characters
+export interface LineRange {
+	startLine: number;
+	startCharacter: number;
+	endLine: number;
+	endCharacter: number;
+}
+
+// Where an opened file's editor landed, so callers can verify the navigation
+export interface OpenFileState {
+	lineCount: number;
+	selections: LineRange[];
+	visibleRange?: LineRange;
+}
+
+// Result of a single open item, git diffs report the repository they used and inserts the new document version
+export type OpenItemResult = {
+	success: boolean;
+	error?: string;
+	repository?: string;
+	state?: OpenFileState;
+	version?: number;
+};
+
+// Result of a tool handler, sent back to the MCP server as the command's response
+export type ToolResult = { success: boolean; data?: unknown; error?: string; code?: string };
+
+// Sends intermediate data of a long-running tool as a partial response, ahead of its final result
+export type PartialResponder = (data: unknown) => Promise<void>;
 
 // Response type for tools
 export type ToolResponse<T> = { success: true; data: T } | { success: false; error: string };
@@ -0,0 +1,168 @@
+import * as vscode from 'vscode';
+import { logger } from '../logger';
+import type { ToolResult } from './types';
+
+export interface PresentationModeRequest {
+	enabled: boolean;
+	fontSize?: number;
+}
+
+// Presentation state of this window. VS Code has no API to read whether Zen mode is on, so only
+// the toggles made here are tracked; the font size holds the workspace value to restore.
+const presentation: { zenMode: boolean; fontSize?: { workspaceValue: unknown; effective: unknown } } = {
+	zenMode: false,
+};
+
+/**
+ * Turns Zen mode on with an optional larger font, or off again restoring the previous font size.
+ */
+export async function presentationMode({ enabled, fontSize }: PresentationModeRequest): Promise<ToolResult> {
+	const configuration = vscode.workspace.getConfiguration('editor');
+	const currentFontSize = configuration.get<number>('fontSize');
+
+	if (!enabled) {
+		if (presentation.zenMode) {
+			await vscode.commands.executeCommand('workbench.action.toggleZenMode');
+			presentation.zenMode = false;
+		}
+		const saved = presentation.fontSize;
+		presentation.fontSize = undefined;
+		if (saved) {
+			await configuration.update('fontSize', saved.workspaceValue, vscode.ConfigurationTarget.Workspace);
+		}
+		return {
+			success: true,
+			data: {
+				enabled: false,
+				zenMode: false,
+				fontSize: vscode.workspace.getConfiguration('editor').get<number>('fontSize'),
+				previousFontSize: currentFontSize,
+			},
+		};
+	}
+
+	if (fontSize !== undefined && fontSize !== currentFontSize) {
+		if (!vscode.workspace.workspaceFolders?.length) {
+			return { success: false, error: 'Changing the font size requires an open folder or workspace' };
+		}
+		// Keep the value from before the first enable, so enabling twice still restores it
+		presentation.fontSize ??= {
+			workspaceValue: configuration.inspect('fontSize')?.workspaceValue,
+			effective: currentFontSize,
+		};
+		await configuration.update('fontSize', fontSize, vscode.ConfigurationTarget.Workspace);
+	}
+	if (!presentation.zenMode) {
+		await vscode.commands.executeCommand('workbench.action.toggleZenMode');
+		presentation.zenMode = true;
+	}
+
+	return {
+		success: true,
+		data: {
+			enabled: true,
+			zenMode: true,
+			fontSize: fontSize ?? currentFontSize,
+			previousFontSize: presentation.fontSize?.effective ?? currentFontSize,
+		},
+	};
+}
+
+export interface ShowCommandsRequest {
+	query?: string;
+	list?: boolean;
+	maxResults?: number;
+}
+
+// Titles of the commands contributed by extensions, by id. Built-in commands have no title in the API.
+function contributedCommandTitles(): Map<string, string> {
+	const titles = new Map<string, string>();
+	for (const extension of vscode.extensions.all) {
+		const commands: Array<{ command: string; title?: unknown; category?: unknown }> =
+			extension.packageJSON.contributes?.commands ?? [];
+		for (const { command, title, category } of commands) {
+			// Untranslated titles are placeholders like %command.title%, they are no use to report
+			if (typeof title === 'string' && !title.startsWith('%')) {
+				const prefix = typeof category === 'string' && !category.startsWith('%') ? `${category}: ` : '';
+				titles.set(command, prefix + title);
+			}
+		}
+	}
+	return titles;
+}
+
+/**
+ * Opens the command palette filtered by a query, or lists the commands matching it.
+ */
+export async function showCommands({
+	query = '',
+	list = false,
+	maxResults = 50,
+}: ShowCommandsRequest): Promise<ToolResult> {
+	if (!list) {
+		await vscode.commands.executeCommand('workbench.action.quickOpen', `>${query}`);
+		return { success: true, data: { opened: true, query } };
+	}
+
+	const titles = contributedCommandTitles();
+	const needle = query.toLowerCase();
+	const matches = (await vscode.commands.getCommands(true))
+		.map((id) => ({ id, title: titles.get(id) ?? null }))
+		.filter(({ id, title }) => id.toLowerCase().includes(needle) || title?.toLowerCase().includes(needle))
+		.sort((a, b) => a.id.localeCompare(b.id));
+	return {
+		success: true,
+		data: { commands: matches.slice(0, maxResults), total: matches.length, truncated: matches.length > maxResults },
+	};
+}
+
+export interface ShowMessageRequest {
+	message: string;
+	kind?: 'info' | 'warning' | 'error';
+	actions?: string[];
+}
+
+/**
+ * Shows a notification. With actions it resolves once the user clicked one or dismissed it.
+ */
+export async function showMessage({ message, kind = 'info', actions = [] }: ShowMessageRequest): Promise<ToolResult> {
+	const show = {
+		info: vscode.window.showInformationMessage,
+		warning: vscode.window.showWarningMessage,
+		error: vscode.window.showErrorMessage,
+	}[kind];
+	if (actions.length === 0) {
+		// Without actions there is nothing to wait for, the notification stays until it times out
+		show(message);
+		return { success: true, data: { shown: true, action: null } };
+	}
+	const action = await show(message, ...actions);
+	return { success: true, data: { shown: true, action: action ?? null } };
+}
+
+// Delay before reloading or closing the window, so the acknowledgement reaches the response file first
+const windowActionDelay = 200;
+
+// Runs a command that tears down the extension host once the tool's response has been written
+function scheduleWindowAction(command: string): ToolResult {
+	setTimeout(() => {
+		vscode.commands.executeCommand(command).then(undefined, (error) => {
+			logger.error('WindowTools', `${command} failed: ${error}`);
+		});
+	}, windowActionDelay);
+	return { success: true };
+}
+
+/**
+ * Reloads the window after acknowledging the request.
+ */
+export function reloadWindow(): ToolResult {
+	return scheduleWindowAction('workbench.action.reloadWindow');
+}
+
+/**
+ * Closes the window after acknowledging the request. VS Code may keep it open to ask about unsaved changes.
+ */
+export function closeWindow(): ToolResult {
+	return scheduleWindowAction('workbench.action.closeWindow');
+}
@@ -0,0 +1,38 @@
+import * as vscode from 'vscode';
+import type { ToolResult } from './types';
+
+export interface ListExtensionsRequest {
+	idPrefix?: string;
+	category?: string;
+}
+
+/**
+ * Lists the installed extensions, optionally narrowed down by id prefix and marketplace category.
+ */
+export function listExtensions({ idPrefix = '', category }: ListExtensionsRequest): ToolResult {
+	const prefix = idPrefix.toLowerCase();
+	const extensions = vscode.extensions.all
+		.filter((extension) => extension.id.toLowerCase().startsWith(prefix))
+		.filter((extension) => !category || (extension.packageJSON.categories ?? []).includes(category))
+		.map((extension) => ({
+			id: extension.id,
+			name: extension.packageJSON.displayName ?? extension.packageJSON.name ?? extension.id,
+			version: extension.packageJSON.version ?? 'unknown',
+			// Disabled extensions are not visible to the extension API
+			enabled: true,
+		}));
+	return { success: true, data: extensions };
+}
+
+/**
+ * Lists the workspace folders in workspace order, with the local path of those on the file system.
+ */
+export function getWorkspaceFolders(): ToolResult {
+	const folders = (vscode.workspace.workspaceFolders ?? []).map((folder) => ({
+		index: folder.index,
+		name: folder.name,
+		...(folder.uri.scheme === 'file' ? { path: folder.uri.fsPath } : {}),
+		uri: folder.uri.toString(),
+	}));
+	return { success: true, data: folders };
+}
@@ -7,9 +7,13 @@ import { type Command, CommandHandler, type CommandResponse } from './command-ha
 import { logger } from './logger';
 
 export interface WindowInfo {
+	windowId: string;
 	workspace: string;
 	windowTitle: string;
 	timestamp: string;
+	pid: number;
+	// Local paths of the workspace folders, used to resolve relative paths
+	folders: string[];
 }
 
 export class WindowManager {
@@ -20,16 +24,20 @@ export class WindowManager {
 	private fileWatcher: fs.FSWatcher | undefined;
 	private responseStream: fs.WriteStream | undefined;
 	private heartbeatInterval: NodeJS.Timeout | undefined;
+	private foldersListener: vscode.Disposable | undefined;
 	private vsClaudeDir: string;
 	private commandHandler: CommandHandler;
+	// Metadata is rewritten when folders change, this keeps the start time
+	private startedAt: string;
 
-	constructor() {
+	constructor(globalStoragePath: string) {
 		this.vsClaudeDir = path.join(os.homedir(), '.vs-claude');
 		this.windowId = this.generateWindowId();
+		this.startedAt = new Date().toISOString();
 		this.commandFile = path.join(this.vsClaudeDir, `${this.windowId}.in`);
 		this.metadataFile = path.join(this.vsClaudeDir, `${this.windowId}.meta.json`);
 		this.responseFile = path.join(this.vsClaudeDir, `${this.windowId}.out`);
-		this.commandHandler = new CommandHandler();
+		this.commandHandler = new CommandHandler(globalStoragePath);
 	}
 
 	async initialize(): Promise<void> {
@@ -41,6 +49,9 @@ export class WindowManager {
 
 		await this.updateWindowMetadata();
 
+		// Keep the folders in the metadata current
+		this.foldersListener = vscode.workspace.onDidChangeWorkspaceFolders(() => this.updateWindowMetadata());
+
 		this.heartbeatInterval = setInterval(() => {
 			const now = new Date();
 			fs.utimesSync(this.metadataFile, now, now);
@@ -54,6 +65,8 @@ export class WindowManager {
 			clearInterval(this.heartbeatInterval);
 		}
 
+		this.foldersListener?.dispose();
+
 		if (this.fileWatcher) {
 			this.fileWatcher.close();
 		}
@@ -72,6 +85,10 @@ export class WindowManager {
 			if (fs.existsSync(this.responseFile)) {
 				fs.unlinkSync(this.responseFile);
 			}
+			// Lock file created by the MCP server around command writes
+			if (fs.existsSync(`${this.commandFile}.lock`)) {
+				fs.unlinkSync(`${this.commandFile}.lock`);
+			}
 		} catch (error) {
 			logger.error('WindowManager', `Cleanup error: ${error}`);
 		}
@@ -117,9 +134,14 @@ export class WindowManager {
 		const windowTitle = vscode.workspace.name || workspace;
 
 		const metadata: WindowInfo = {
+			windowId: this.windowId,
 			workspace,
 			windowTitle,
-			timestamp: new Date().toISOString(),
+			timestamp: this.startedAt,
+			pid: process.pid,
+			folders: (vscode.workspace.workspaceFolders ?? [])
+				.filter((folder) => folder.uri.scheme === 'file')
+				.map((folder) => folder.uri.fsPath),
 		};
 
 		fs.writeFileSync(this.metadataFile, JSON.stringify(metadata, null, 2));
@@ -150,6 +172,10 @@ export class WindowManager {
 			if (eventType === 'change') {
 				try {
 					const stats = fs.statSync(this.commandFile);
+					// The MCP server truncates the file once every command in it was answered
+					if (stats.size < lastPosition) {
+						lastPosition = 0;
+					}
 					if (stats.size > lastPosition) {
 						// Read only new data
 						const fd = fs.openSync(this.commandFile, 'r');
@@ -191,7 +217,9 @@ export class WindowManager {
 								logger.command(command.tool);
 
 								// Execute the command
-								const result = await this.commandHandler.executeCommand(command);
+								const result = await this.commandHandler.executeCommand(command, (data) =>
+									this.writeResponse({ id: command.id, success: true, data, partial: true })
+								);
 
 								// Always write response for better reliability
 								const response: CommandResponse = {
@@ -199,6 +227,7 @@ export class WindowManager {
 									success: result.success,
 									data: result.data,
 									error: result.error,
+									code: result.code,
 								};
 								await this.writeResponse(response);
 							} catch (error) {
@@ -1,7 +1,25 @@
 import * as assert from 'assert';
+import * as fs from 'fs';
+import * as os from 'os';
 import * as path from 'path';
 import * as vscode from 'vscode';
+import { backupDiff } from '../../src/tools/backup-tools';
+import { getConfig } from '../../src/tools/config-tools';
+import { clearBreakpoint, listBreakpoints, setBreakpoint } from '../../src/tools/debug-tools';
+import { getDiagnostics, watchDiagnostics } from '../../src/tools/diagnostics-tools';
+import {
+	getActiveEditor,
+	getFileContent,
+	getSelection,
+	saveAndClose,
+	setSelection,
+} from '../../src/tools/editor-tools';
+import { getGitStatus, gitBlame, gitLog } from '../../src/tools/git-tools';
 import { OpenHandler } from '../../src/tools/open-tool';
+import { fromUtf16Offset, toUtf16Offset } from '../../src/tools/positions';
+import { listTodos, search } from '../../src/tools/search-tools';
+import { runScript, terminal } from '../../src/tools/terminal-tools';
+import { getWorkspaceFolders, listExtensions } from '../../src/tools/workspace-tools';
 import type { OpenRequest } from '../../src/tools/types';
 
 /**
@@ -79,6 +97,29 @@ suite('Individual Tools Unit Tests', function () {
 			}
 		});
 
+		test('Should report the editor state of grouped file items', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const result = await openHandler.execute([
+				{ type: 'file', path: filePath, startLine: 2, endLine: 3 },
+				{ type: 'file', path: filePath, startLine: 5 },
+			]);
+			assert.ok(result.success, 'Should succeed');
+
+			const document = await vscode.workspace.openTextDocument(filePath);
+			const [first, second] = result.data ?? [];
+			assert.ok(first.state, 'Should report the editor state');
+			assert.strictEqual(first.state.lineCount, document.lineCount);
+			assert.deepStrictEqual(
+				first.state.selections.map((selection) => [selection.startLine, selection.endLine]),
+				[
+					[2, 3],
+					[5, 5],
+				],
+				'Should report one selection per item'
+			);
+			assert.deepStrictEqual(second.state, first.state, 'Items of the same file share the state');
+		});
+
 		test('Should open multiple files', async () => {
 			const requests: OpenRequest[] = [
 				{
@@ -121,5 +162,476 @@ suite('Individual Tools Unit Tests', function () {
 				assert.ok(result.error?.includes('Failed to open'), 'Should have error message');
 			}
 		});
+
+		test('Should reveal a workspace file in the Explorer', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const result = await openHandler.execute([{ type: 'reveal', path: filePath }]);
+			assert.ok(result.success, 'Should succeed');
+		});
+
+		test('Should refuse to reveal paths outside the workspace', async () => {
+			const outside = path.dirname(vscode.workspace.workspaceFolders?.[0].uri.fsPath ?? '/');
+			const result = await openHandler.execute([{ type: 'reveal', path: outside }]);
+			assert.ok(!result.success, 'Should fail');
+			assert.ok(result.error?.includes('outside all workspace folders'), 'Should name the reason');
+		});
+
+		test('Should create a file with its parent directories', async () => {
+			const dir = path.join(os.tmpdir(), `vs-claude-create-${Date.now()}`);
+			const filePath = path.join(dir, 'nested', 'new.txt');
+			try {
+				const created = await openHandler.execute([{ type: 'createFile', path: filePath, content: 'new\n' }]);
+				assert.ok(created.success, 'Should succeed');
+				assert.strictEqual(fs.readFileSync(filePath, 'utf8'), 'new\n');
+				const active = vscode.window.activeTextEditor?.document.uri.fsPath;
+				assert.strictEqual(active, filePath, 'Should open the file');
+
+				const again = await openHandler.execute([{ type: 'createFile', path: filePath, content: 'other\n' }]);
+				assert.ok(!again.success, 'Should not replace an existing file');
+				assert.ok(again.error?.includes('overwrite'), 'Should point to overwrite');
+				assert.strictEqual(fs.readFileSync(filePath, 'utf8'), 'new\n', 'Should leave the file alone');
+			} finally {
+				await vscode.commands.executeCommand('workbench.action.closeActiveEditor');
+				fs.rmSync(dir, { recursive: true, force: true });
+			}
+		});
+
+		test('Should create a file with the requested line endings and BOM', async () => {
+			const filePath = path.join(os.tmpdir(), `vs-claude-eol-${Date.now()}.bat`);
+			try {
+				const result = await openHandler.execute([
+					{ type: 'createFile', path: filePath, content: 'a\nb\n', eol: 'crlf', bom: true },
+				]);
+				assert.ok(result.success, 'Should succeed');
+				assert.strictEqual(fs.readFileSync(filePath, 'utf8'), '\uFEFFa\r\nb\r\n');
+			} finally {
+				await vscode.commands.executeCommand('workbench.action.closeActiveEditor');
+				fs.rmSync(filePath, { force: true });
+			}
+		});
+
+		test('Should insert text in the document line endings and report the version', async () => {
+			const filePath = path.join(os.tmpdir(), `vs-claude-insert-${Date.now()}.txt`);
+			fs.writeFileSync(filePath, 'one\r\ntwo\r\n');
+			try {
+				const result = await openHandler.execute([
+					{ type: 'insert', path: filePath, line: 2, character: 0, text: 'new\nlines\n', select: true },
+				]);
+				assert.ok(result.success, 'Should succeed');
+				assert.ok(typeof result.data?.[0].version === 'number', 'Should report the version');
+
+				const editor = vscode.window.activeTextEditor;
+				assert.ok(editor, 'Should show the document');
+				assert.strictEqual(editor.document.getText(), 'one\r\nnew\r\nlines\r\ntwo\r\n');
+				const inserted = new vscode.Selection(1, 0, 3, 0);
+				assert.ok(editor.selection.isEqual(inserted), 'Should select the inserted lines');
+			} finally {
+				await vscode.commands.executeCommand('workbench.action.revertAndCloseActiveEditor');
+				fs.rmSync(filePath, { force: true });
+			}
+		});
+
+		test('Should report the changes of a dry run without making them', async () => {
+			const existing = path.join(os.tmpdir(), `vs-claude-dry-${Date.now()}.txt`);
+			const created = path.join(os.tmpdir(), `vs-claude-dry-new-${Date.now()}.txt`);
+			fs.writeFileSync(existing, 'one\ntwo\n');
+			try {
+				const result = await openHandler.dryRun([
+					{ type: 'insert', path: existing, line: 2, character: 0, text: 'new\n' },
+					{ type: 'createFile', path: existing, content: 'replaced\n', overwrite: true },
+					{ type: 'createFile', path: created, content: 'new\n' },
+					{ type: 'reveal', path: existing },
+				]);
+				assert.ok(result.success, 'Should succeed');
+				const range = (startLine: number, endLine: number, endCharacter: number) => ({
+					startLine,
+					startCharacter: 0,
+					endLine,
+					endCharacter,
+				});
+				assert.deepStrictEqual(result.data, {
+					dryRun: true,
+					changes: [
+						{ path: existing, edits: [{ range: range(2, 2, 0), newText: 'new\n' }] },
+						{ path: existing, edits: [{ range: range(1, 3, 0), newText: 'replaced\n' }] },
+						{ path: created, operation: 'create' },
+					],
+				});
+				assert.strictEqual(fs.readFileSync(existing, 'utf8'), 'one\ntwo\n', 'Should leave the file alone');
+				assert.ok(!fs.existsSync(created), 'Should not create the file');
+
+				const refused = await openHandler.dryRun([{ type: 'createFile', path: existing, content: 'x' }]);
+				assert.ok(!refused.success, 'Should fail like the real run without overwrite');
+			} finally {
+				fs.rmSync(existing, { force: true });
+			}
+		});
+	});
+
+	suite('Editor Tools', () => {
+		test('Should report the active editor', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const result = await openHandler.execute([{ type: 'file', path: filePath, startLine: 5, endLine: 7 }]);
+			assert.ok(result.success, 'Should open the file');
+
+			const active = getActiveEditor();
+			assert.ok(active.success, 'Should succeed');
+			const data = active.data as {
+				active: { path: string; languageId: string; selection: { startLine: number; endLine: number } };
+			};
+			assert.strictEqual(data.active.path, filePath);
+			assert.strictEqual(data.active.languageId, 'typescript');
+			assert.strictEqual(data.active.selection.startLine, 5, 'Selection should start at line 5 (1-based)');
+			assert.strictEqual(data.active.selection.endLine, 7, 'Selection should end at line 7 (1-based)');
+		});
+
+		test('Should report the selected text', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const range = new vscode.Range(2, 0, 3, 4);
+			const editor = await vscode.window.showTextDocument(vscode.Uri.file(filePath), { selection: range });
+
+			const result = getSelection();
+			assert.ok(result.success, 'Should succeed');
+			const [selection] = result.data as Array<{ path: string; text: string; range: { startLine: number } }>;
+			assert.strictEqual(selection.text, editor.document.getText(range));
+			assert.strictEqual(selection.range.startLine, 3, 'Lines should be 1-based');
+		});
+
+		test('Should set backward selections and clamp positions', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const result = await setSelection({
+				path: filePath,
+				selections: [{ start: { line: 3, character: 4 }, end: { line: 2, character: 10000 } }],
+			});
+			assert.ok(result.success, 'Should succeed');
+			const data = result.data as { selections: Array<{ startLine: number; endLine: number }>; clamped: boolean };
+			assert.ok(data.clamped, 'Should report the clamped character');
+			assert.strictEqual(data.selections[0].startLine, 3, 'Should keep the anchor');
+			assert.strictEqual(data.selections[0].endLine, 2, 'Should keep the direction');
+		});
+
+		test('Should read unsaved changes from the editor buffer', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const document = await vscode.workspace.openTextDocument(filePath);
+			await vscode.window.showTextDocument(document);
+			const edit = new vscode.WorkspaceEdit();
+			edit.insert(document.uri, new vscode.Position(0, 0), '// unsaved\n');
+			await vscode.workspace.applyEdit(edit);
+			try {
+				const result = getFileContent({ path: filePath, startLine: 1, endLine: 1 });
+				assert.ok(result.success, 'Should succeed');
+				const data = result.data as { source: string; dirty: boolean; content: string };
+				assert.strictEqual(data.source, 'buffer');
+				assert.ok(data.dirty, 'Should be dirty');
+				assert.strictEqual(data.content, '// unsaved');
+			} finally {
+				await vscode.commands.executeCommand('workbench.action.files.revert');
+			}
+		});
+
+		test('Should save unsaved changes before closing', async () => {
+			const filePath = path.join(os.tmpdir(), `vs-claude-save-${Date.now()}.txt`);
+			fs.writeFileSync(filePath, 'before\n');
+			try {
+				const document = await vscode.workspace.openTextDocument(filePath);
+				await vscode.window.showTextDocument(document);
+				const edit = new vscode.WorkspaceEdit();
+				edit.insert(document.uri, new vscode.Position(0, 0), 'after\n');
+				await vscode.workspace.applyEdit(edit);
+
+				const result = await saveAndClose({ paths: [filePath] });
+				assert.ok(result.success, 'Should succeed');
+				assert.deepStrictEqual(result.data, { closed: [filePath], saved: [filePath], failed: [] });
+				assert.strictEqual(fs.readFileSync(filePath, 'utf8'), 'after\nbefore\n', 'Should have saved');
+				const open = vscode.window.tabGroups.all.flatMap((group) => group.tabs);
+				assert.ok(
+					!open.some((tab) => tab.input instanceof vscode.TabInputText && tab.input.uri.fsPath === filePath),
+					'Should have closed the editor'
+				);
+			} finally {
+				fs.rmSync(filePath, { force: true });
+			}
+		});
+	});
+
+	suite('Config Tools', () => {
+		test('Should report the scope of a workspace setting', async () => {
+			const result = await getConfig({ key: 'go.useLanguageServer' });
+			assert.ok(result.success, 'Should succeed');
+			assert.deepStrictEqual(result.data, { key: 'go.useLanguageServer', value: true, scope: 'workspace' });
+		});
+
+		test('Should report undefined settings as none', async () => {
+			const result = await getConfig({ key: 'vsClaudeTest.undefinedSetting' });
+			assert.ok(result.success, 'Should succeed');
+			assert.deepStrictEqual(result.data, { key: 'vsClaudeTest.undefinedSetting', value: null, scope: 'none' });
+		});
+	});
+
+	suite('Backup Tools', () => {
+		// A user data directory laid out like VS Code's, with the extension's global storage in it
+		function userDataDir(): { backupsDir: string; globalStoragePath: string } {
+			const dir = path.join(os.tmpdir(), `vs-claude-backups-${Date.now()}`);
+			return {
+				backupsDir: path.join(dir, 'Backups'),
+				globalStoragePath: path.join(dir, 'User', 'globalStorage', 'mariozechner.vs-claude'),
+			};
+		}
+
+		test('Should report files without a backup', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const { globalStoragePath } = userDataDir();
+			const result = await backupDiff({ path: filePath }, globalStoragePath);
+			assert.ok(result.success, 'Should succeed');
+			assert.deepStrictEqual(result.data, { backup: false, message: `no backup available for ${filePath}` });
+		});
+
+		test('Should diff a file against its backup', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const { backupsDir, globalStoragePath } = userDataDir();
+			const schemeDir = path.join(backupsDir, '1234567890', 'file');
+			fs.mkdirSync(schemeDir, { recursive: true });
+			const resource = vscode.Uri.file(filePath).toString();
+			fs.writeFileSync(path.join(schemeDir, 'abcdef'), `${resource} {"mtime":0}\nunsaved content\n`);
+			try {
+				const result = await backupDiff({ path: filePath }, globalStoragePath);
+				assert.ok(result.success, 'Should succeed');
+				assert.strictEqual((result.data as { backup: boolean }).backup, true);
+
+				const input = vscode.window.tabGroups.activeTabGroup.activeTab?.input;
+				assert.ok(input instanceof vscode.TabInputTextDiff, 'Should open a diff');
+				assert.strictEqual(input.original.fsPath, filePath);
+				const backup = await vscode.workspace.openTextDocument(input.modified);
+				assert.strictEqual(backup.getText(), 'unsaved content\n');
+			} finally {
+				fs.rmSync(path.dirname(backupsDir), { recursive: true, force: true });
+			}
+		});
+	});
+
+	suite('Workspace Tools', () => {
+		test('Should list extensions by id prefix', () => {
+			const result = listExtensions({ idPrefix: 'VSCODE.GIT' });
+			assert.ok(result.success, 'Should succeed');
+			const ids = (result.data as Array<{ id: string }>).map((extension) => extension.id);
+			assert.ok(ids.includes('vscode.git'), 'Should include the built-in git extension');
+			assert.ok(
+				ids.every((id) => id.startsWith('vscode.git')),
+				'Should only include extensions with the prefix'
+			);
+		});
+
+		test('Should list the workspace folders', () => {
+			const result = getWorkspaceFolders();
+			assert.ok(result.success, 'Should succeed');
+			const folder = vscode.workspace.workspaceFolders?.[0];
+			assert.ok(folder, 'Test workspace should have a folder');
+			const folders = result.data as Array<{ index: number; name: string; path?: string; uri: string }>;
+			assert.deepStrictEqual(folders[0], {
+				index: 0,
+				name: folder.name,
+				path: folder.uri.fsPath,
+				uri: folder.uri.toString(),
+			});
+		});
+	});
+
+	suite('Terminal Tools', () => {
+		test('Should list the scripts with their source', async () => {
+			const result = await runScript({});
+			assert.ok(result.success, 'Should succeed');
+			const { scripts } = result.data as { scripts: Array<{ name: string; source: string }> };
+			assert.ok(Array.isArray(scripts), 'Should list scripts');
+		});
+
+		test('Should fail for unknown scripts', async () => {
+			const result = await runScript({ name: 'no-such-script' });
+			assert.ok(!result.success, 'Should fail');
+			assert.ok(result.error?.includes('Available scripts'), 'Should list the available scripts');
+		});
+
+		test('Should reuse a terminal by name', async () => {
+			const name = 'vs-claude-test';
+			try {
+				const first = await terminal({ command: 'echo first', name, show: false, reuse: true });
+				assert.ok(first.success, 'Should succeed');
+				assert.strictEqual((first.data as { created: boolean }).created, true, 'Should create the terminal');
+				const second = await terminal({ command: 'echo second', name, show: false, reuse: true });
+				assert.strictEqual((second.data as { created: boolean }).created, false, 'Should reuse the terminal');
+			} finally {
+				for (const open of vscode.window.terminals.filter((candidate) => candidate.name === name)) {
+					open.dispose();
+				}
+			}
+		});
+	});
+
+	suite('Diagnostics Tools', () => {
+		test('Should filter diagnostics by source and severity', () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const collection = vscode.languages.createDiagnosticCollection('vs-claude-test');
+			try {
+				const range = new vscode.Range(2, 0, 2, 5);
+				const error = new vscode.Diagnostic(range, 'test error', vscode.DiagnosticSeverity.Error);
+				error.source = 'vs-claude-test';
+				const warning = new vscode.Diagnostic(range, 'test warning', vscode.DiagnosticSeverity.Warning);
+				warning.source = 'vs-claude-test';
+				collection.set(vscode.Uri.file(filePath), [error, warning]);
+
+				const result = getDiagnostics({ path: filePath, source: 'vs-claude-test', severity: 'error' });
+				assert.ok(result.success, 'Should succeed');
+				const data = result.data as {
+					sources: string[];
+					diagnostics: Array<{ message: string; range: { startLine: number } }>;
+				};
+				assert.ok(data.sources.includes('vs-claude-test'), 'Should list the source');
+				assert.strictEqual(data.diagnostics.length, 1, 'Should only include the error');
+				assert.strictEqual(data.diagnostics[0].message, 'test error');
+				assert.strictEqual(data.diagnostics[0].range.startLine, 3, 'Lines should be 1-based');
+			} finally {
+				collection.dispose();
+			}
+		});
+
+		test('Should stream diagnostics until clean', async () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			const collection = vscode.languages.createDiagnosticCollection('vs-claude-watch');
+			try {
+				const error = new vscode.Diagnostic(new vscode.Range(0, 0, 0, 1), 'watched error');
+				error.source = 'vs-claude-watch';
+				collection.set(vscode.Uri.file(filePath), [error]);
+
+				const snapshots: Array<{ diagnostics: unknown[] }> = [];
+				const request = { path: filePath, source: 'vs-claude-watch', duration: 10, untilClean: true };
+				const watching = watchDiagnostics(request, async (data) => {
+					snapshots.push(data as { diagnostics: unknown[] });
+				});
+				await new Promise((resolve) => setTimeout(resolve, 100));
+				collection.clear();
+
+				const result = await watching;
+				assert.ok(result.success, 'Should succeed');
+				assert.ok(snapshots.length >= 2, 'Should send the initial and the clean snapshot');
+				assert.ok(snapshots[0].diagnostics.length > 0, 'Should start with the error');
+				assert.strictEqual(snapshots[snapshots.length - 1].diagnostics.length, 0, 'Should end clean');
+			} finally {
+				collection.dispose();
+			}
+		});
+	});
+
+	suite('Debug Tools', () => {
+		test('Should replace, list and clear breakpoints', () => {
+			const filePath = getTestFilePath('typescript/user.service.ts');
+			try {
+				setBreakpoint({ path: filePath, line: 5 });
+				const updated = setBreakpoint({ path: filePath, line: 5, condition: 'x > 5' });
+				assert.deepStrictEqual(updated.data, { path: filePath, line: 5, enabled: true, condition: 'x > 5' });
+
+				const listed = listBreakpoints({ path: filePath });
+				assert.deepStrictEqual(listed.data, [updated.data], 'Should have replaced the first breakpoint');
+
+				assert.deepStrictEqual(clearBreakpoint({ path: filePath, line: 5 }).data, { removed: 1 });
+				assert.deepStrictEqual(listBreakpoints({ path: filePath }).data, []);
+			} finally {
+				clearBreakpoint({ path: filePath });
+			}
+		});
+	});
+
+	suite('Positions', () => {
+		// 'é' is 2 UTF-8 bytes and 1 UTF-16 unit, the emoji 4 bytes and 2 units
+		const line = 'aé😀b';
+
+		test('Should convert offsets to UTF-16 code units', () => {
+			assert.strictEqual(toUtf16Offset(line, 7, 'utf8'), 4, 'Byte offset of b');
+			assert.strictEqual(toUtf16Offset(line, 3, 'codepoint'), 4, 'Code point offset of b');
+			assert.strictEqual(toUtf16Offset(line, 4, 'utf16'), 4, 'UTF-16 offsets are kept');
+			assert.strictEqual(toUtf16Offset(line, 4, 'utf8'), 4, 'Offsets inside a character land after it');
+			assert.strictEqual(toUtf16Offset(line, 10, 'utf8'), 7, 'Offsets past the end stay past it');
+		});
+
+		test('Should convert UTF-16 code units to offsets', () => {
+			assert.strictEqual(fromUtf16Offset(line, 4, 'utf8'), 7);
+			assert.strictEqual(fromUtf16Offset(line, 4, 'codepoint'), 3);
+			assert.strictEqual(fromUtf16Offset(line, 4, undefined), 4);
+		});
+	});
+
+	suite('Git Tools', () => {
+		test('Should list commits with their full message', async () => {
+			const repo = path.dirname(getTestFilePath('.'));
+			const result = await gitLog({ repo, max: 1 });
+			assert.ok(result.success, 'Should succeed');
+			const { commits } = result.data as {
+				commits: Array<{ hash: string; parents: string[]; message: string }>;
+			};
+			assert.strictEqual(commits.length, 1, 'Should respect max');
+			assert.strictEqual(commits[0].hash.length, 40, 'Should report full hashes');
+			assert.strictEqual(commits[0].message, 'Initial test commit');
+			assert.deepStrictEqual(commits[0].parents, [], 'The initial commit has no parents');
+		});
+
+		test('Should blame a line range', async () => {
+			const result = await gitBlame({ path: getTestFilePath('go/user_service.go'), startLine: 2, endLine: 3 });
+			assert.ok(result.success, 'Should succeed');
+			const { lines } = result.data as { lines: Array<{ line: number; summary: string }> };
+			assert.deepStrictEqual(
+				lines.map((line) => line.line),
+				[2, 3],
+				'Should report the final line numbers'
+			);
+			assert.ok(lines.every((line) => line.summary === 'Initial test commit'));
+		});
+
+		test('Should report modified files as unstaged', async () => {
+			const repo = path.dirname(getTestFilePath('.'));
+			const result = await getGitStatus({ repo });
+			assert.ok(result.success, 'Should succeed');
+			const { unstaged, staged } = result.data as {
+				unstaged: Array<{ status: string; path: string }>;
+				staged: Array<{ status: string; path: string }>;
+			};
+			const modified = getTestFilePath('typescript/user.service.ts');
+			assert.ok(
+				unstaged.some((entry) => entry.status === 'M' && entry.path === modified),
+				'Should list the uncommitted modification'
+			);
+			assert.ok(!staged.some((entry) => entry.path === modified), 'Should not list it as staged');
+		});
+	});
+
+	suite('Search Tools', () => {
+		test('Should find text with 1-based lines and 0-based characters', async () => {
+			const result = await search({ query: 'class UserService', includeGlob: '**/*.ts' });
+			assert.ok(result.success, 'Should succeed');
+			const { matches, truncated } = result.data as {
+				matches: Array<{ path: string; line: number; character: number; text: string }>;
+				truncated: boolean;
+			};
+			assert.ok(!truncated, 'Should not be truncated');
+			const match = matches.find((m) => m.path === getTestFilePath('typescript/user.service.ts'));
+			assert.ok(match, 'Should find the class declaration');
+			assert.strictEqual(match.text.indexOf('class UserService'), match.character);
+		});
+
+		test('Should report truncation', async () => {
+			const result = await search({ query: 'User', maxResults: 1 });
+			assert.ok(result.success, 'Should succeed');
+			const { matches, truncated } = result.data as { matches: unknown[]; truncated: boolean };
+			assert.strictEqual(matches.length, 1);
+			assert.ok(truncated, 'Should be truncated');
+		});
+
+		test('Should list tags as whole words', async () => {
+			const result = await listTodos({ tags: ['UserService'], include: '**/*.ts' });
+			assert.ok(result.success, 'Should succeed');
+			const { todos } = result.data as { todos: Array<{ tag: string }> };
+			assert.ok(todos.length > 0, 'Should find the tag');
+			assert.ok(todos.every((todo) => todo.tag === 'UserService'));
+
+			const partial = await listTodos({ tags: ['UserServic'], include: '**/*.ts' });
+			assert.deepStrictEqual((partial.data as { todos: unknown[] }).todos, [], 'Should not match inside words');
+		});
 	});
 });